@@ -0,0 +1,151 @@
+package goldb
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+)
+
+// Aggregate is result of aggregation of values of records
+type Aggregate struct {
+	Count uint64
+	Sum   float64
+	Min   float64
+	Max   float64
+}
+
+func (a *Aggregate) String() string {
+	return fmt.Sprintf("{count:%d, sum:%v, min:%v, max:%v}", a.Count, a.Sum, a.Min, a.Max)
+}
+
+// Avg returns average of values
+func (a *Aggregate) Avg() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+	return a.Sum / float64(a.Count)
+}
+
+func (a *Aggregate) add(v float64) {
+	if a.Count == 0 || v < a.Min {
+		a.Min = v
+	}
+	if a.Count == 0 || v > a.Max {
+		a.Max = v
+	}
+	a.Count++
+	a.Sum += v
+}
+
+// Aggregate calculates count, sum, min and max of values of records by query in one pass.
+// Function fnValue returns numeric value of record; when fnValue is nil only records are counted.
+//
+//	agg, err := store.Aggregate(NewQuery(TabOrders), func(rec Record) float64 {
+//		return float64(rec.ValueInt())
+//	})
+func (c *context) Aggregate(q *Query, fnValue func(Record) float64) (agg Aggregate, err error) {
+	err = c.execute(q, func(rec Record) error {
+		if fnValue != nil {
+			agg.add(fnValue(rec))
+		} else {
+			agg.Count++
+		}
+		return nil
+	})
+	return
+}
+
+// GroupBy calculates aggregates of values of records by query grouped by key-value with index keyPart.
+// Parameters keyVars are pointers to variables of types of key-values (as in Record.DecodeKey).
+//
+//	var day, userID int
+//	groups, err := store.GroupBy(NewQuery(TabOrders), 0, []interface{}{&day, &userID}, fnValue)
+func (c *context) GroupBy(q *Query, keyPart int, keyVars []interface{}, fnValue func(Record) float64) (groups map[interface{}]*Aggregate, err error) {
+	groups = map[interface{}]*Aggregate{}
+	err = c.groupBy(q, keyPart, keyVars, func(group interface{}, rec Record) {
+		agg := groups[group]
+		if agg == nil {
+			agg = &Aggregate{}
+			groups[group] = agg
+		}
+		if fnValue != nil {
+			agg.add(fnValue(rec))
+		} else {
+			agg.Count++
+		}
+	})
+	return
+}
+
+// IntAggregate is result of aggregation of integer values of records. Sum is calculated without overflow
+type IntAggregate struct {
+	Count uint64
+	Sum   *big.Int
+	Min   int64
+	Max   int64
+}
+
+func newIntAggregate() *IntAggregate {
+	return &IntAggregate{Sum: big.NewInt(0)}
+}
+
+func (a *IntAggregate) String() string {
+	return fmt.Sprintf("{count:%d, sum:%v, min:%v, max:%v}", a.Count, a.Sum, a.Min, a.Max)
+}
+
+func (a *IntAggregate) add(v int64) {
+	if a.Count == 0 || v < a.Min {
+		a.Min = v
+	}
+	if a.Count == 0 || v > a.Max {
+		a.Max = v
+	}
+	a.Count++
+	a.Sum.Add(a.Sum, big.NewInt(v))
+}
+
+// AggregateInt calculates count, sum, min and max of integer values of records by query in one pass
+//
+//	agg, err := store.AggregateInt(NewQuery(TabOrders), func(rec Record) int64 {
+//		return rec.ValueInt()
+//	})
+func (c *context) AggregateInt(q *Query, fnValue func(Record) int64) (agg *IntAggregate, err error) {
+	agg = newIntAggregate()
+	err = c.execute(q, func(rec Record) error {
+		agg.add(fnValue(rec))
+		return nil
+	})
+	return
+}
+
+// GroupByInt calculates aggregates of integer values of records by query grouped by key-value with index keyPart
+func (c *context) GroupByInt(q *Query, keyPart int, keyVars []interface{}, fnValue func(Record) int64) (groups map[interface{}]*IntAggregate, err error) {
+	groups = map[interface{}]*IntAggregate{}
+	err = c.groupBy(q, keyPart, keyVars, func(group interface{}, rec Record) {
+		agg := groups[group]
+		if agg == nil {
+			agg = newIntAggregate()
+			groups[group] = agg
+		}
+		agg.add(fnValue(rec))
+	})
+	return
+}
+
+// groupBy calls function fn for records by query with key-value with index keyPart
+func (c *context) groupBy(q *Query, keyPart int, keyVars []interface{}, fn func(group interface{}, rec Record)) error {
+	if keyPart < 0 || keyPart >= len(keyVars) {
+		return fmt.Errorf("goldb: invalid index of key part %d", keyPart)
+	}
+	return c.execute(q, func(rec Record) error {
+		if err := rec.DecodeKey(keyVars[:keyPart+1]...); err != nil {
+			return err
+		}
+		group := reflect.ValueOf(keyVars[keyPart]).Elem().Interface()
+		if b, ok := group.([]byte); ok {
+			group = string(b)
+		}
+		fn(group, rec)
+		return nil
+	})
+}
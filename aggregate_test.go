@@ -0,0 +1,80 @@
+package goldb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext_Aggregate(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, "A", 1), 10)
+		tr.PutVar(Key(TestTable, "A", 2), -5)
+		tr.PutVar(Key(TestTable, "B", 3), 7)
+	})
+	fnValue := func(rec Record) float64 {
+		return float64(rec.ValueInt())
+	}
+
+	agg, err := store.Aggregate(NewQuery(TestTable), fnValue)
+	aggA, errA := store.Aggregate(NewQuery(TestTable, "A"), nil)
+
+	assert.NoError(t, err)
+	assert.NoError(t, errA)
+	assert.Equal(t, Aggregate{Count: 3, Sum: 12, Min: -5, Max: 10}, agg)
+	assert.Equal(t, 4.0, agg.Avg())
+	assert.Equal(t, Aggregate{Count: 2}, aggA)
+}
+
+func TestTransaction_GroupBy(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	var groups map[interface{}]*Aggregate
+	var err error
+	store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, "A", 1), 10)
+		tr.PutVar(Key(TestTable, "A", 2), -5)
+		tr.PutVar(Key(TestTable, "B", 3), 7)
+
+		var name string
+		var id int
+		groups, err = tr.GroupBy(NewQuery(TestTable), 0, []interface{}{&name, &id}, func(rec Record) float64 {
+			return float64(rec.ValueInt())
+		})
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(groups))
+	assert.Equal(t, Aggregate{Count: 2, Sum: 5, Min: -5, Max: 10}, *groups["A"])
+	assert.Equal(t, Aggregate{Count: 1, Sum: 7, Min: 7, Max: 7}, *groups["B"])
+}
+
+func TestContext_AggregateInt(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, "A", 1), int64(1)<<62)
+		tr.PutVar(Key(TestTable, "A", 2), int64(1)<<62+1)
+		tr.PutVar(Key(TestTable, "B", 3), int64(-7))
+	})
+	fnValue := func(rec Record) int64 {
+		return rec.ValueInt()
+	}
+
+	agg, err := store.AggregateInt(NewQuery(TestTable), fnValue)
+	var name string
+	var id int
+	groups, errG := store.GroupByInt(NewQuery(TestTable), 0, []interface{}{&name, &id}, fnValue)
+
+	assert.NoError(t, err)
+	assert.NoError(t, errG)
+	assert.Equal(t, "9223372036854775802", agg.Sum.String()) // 2^63 + 1 - 7
+	assert.Equal(t, uint64(3), agg.Count)
+	assert.Equal(t, int64(-7), agg.Min)
+	assert.Equal(t, int64(1)<<62+1, agg.Max)
+	assert.Equal(t, "9223372036854775809", groups["A"].Sum.String()) // 2^63 + 1
+	assert.Equal(t, "-7", groups["B"].Sum.String())
+}
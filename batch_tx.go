@@ -1,12 +1,31 @@
 package goldb
 
 import (
+	gocontext "context"
 	"sync/atomic"
 
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
 func (s *Storage) ExecBatch(fn func(tx *Transaction)) error {
+	return s.ExecBatchCtx(gocontext.Background(), fn)
+}
+
+// ExecBatchCtx executes transaction in batch with context.
+// When ctx is done before the batch started, the transaction is marked as abandoned (it is skipped on batch execution)
+// and ctx.Err() is returned.
+// When the batch is already executing, ExecBatchCtx waits for its result.
+func (s *Storage) ExecBatchCtx(ctx gocontext.Context, fn func(tx *Transaction)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var abandoned bool
+	txFn := func(tx *Transaction) {
+		if !abandoned {
+			fn(tx)
+		}
+	}
+
 	atomic.AddInt64(&s.cntWaitingTrans, 1)
 	defer atomic.AddInt64(&s.cntWaitingTrans, -1)
 
@@ -20,14 +39,26 @@ func (s *Storage) ExecBatch(fn func(tx *Transaction)) error {
 		s.batchCl, s.batchErr = make(chan struct{}), new(error)
 	}
 	cl, pErr := s.batchCl, s.batchErr
-	s.batchTxs = append(s.batchTxs, fn)
+	s.batchTxs = append(s.batchTxs, txFn)
 	if len(s.batchTxs) == 1 {
 		close(s.batchExst)
 	}
 	s.batchMx.Unlock()
 	//---
 
-	<-cl // waiting for batch commit
+	select {
+	case <-cl: // waiting for batch commit
+	case <-ctx.Done():
+		s.batchMx.Lock()
+		if s.batchCl == cl { // batch is not started yet
+			abandoned = true
+		}
+		s.batchMx.Unlock()
+		if abandoned {
+			return ctx.Err()
+		}
+		<-cl
+	}
 	return *pErr
 }
 
@@ -0,0 +1,143 @@
+package goldb
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	tabChangeLog     Entity = 0x7ffffffc // (seq) -> changed key
+	tabChangeLogMeta Entity = 0x7ffffff7 // meta-records of change log
+)
+
+var (
+	ErrChangeLogDisabled  = errors.New("goldb: change log is disabled")
+	ErrChangeLogTruncated = errors.New("goldb: change log is truncated")
+)
+
+func init() {
+	SetKeyEncoding(tabChangeLog, KeyEncodingOrdered)
+}
+
+var (
+	changeLogSeqKey       = Key(tabChangeLogMeta, "seq")       // sequence number of last change
+	changeLogRetentionKey = Key(tabChangeLogMeta, "retention") // max number of records of change log
+)
+
+// EnableChangeLog enables persistent log of keys changed by Exec and ExecBatch.
+// The change log is used by incremental dumps.
+// The change log keeps up to retention last records, older records are removed automatically
+// (retention 0 keeps all records until TruncateChangeLog).
+func (s *Storage) EnableChangeLog(retention uint64) error {
+	var seq uint64
+	if err := s.Exec(func(tr *Transaction) {
+		if ok, _ := tr.GetVar(changeLogSeqKey, &seq); !ok {
+			tr.put(changeLogSeqKey, encodeValue(uint64(0)))
+		}
+		tr.put(changeLogRetentionKey, encodeValue(retention))
+		atomic.StoreInt32(&s.changeLog, 1)
+	}); err != nil {
+		return err
+	}
+	if retention == 0 || seq <= retention {
+		return nil
+	}
+	return s.TruncateChangeLog(seq - retention)
+}
+
+// ChangeLogEnabled returns true when change log is enabled
+func (s *Storage) ChangeLogEnabled() bool {
+	return atomic.LoadInt32(&s.changeLog) != 0
+}
+
+// ChangeLogSeq returns sequence number of last change in the change log
+func (s *Storage) ChangeLogSeq() (seq uint64, err error) {
+	_, err = s.GetVar(changeLogSeqKey, &seq)
+	return
+}
+
+// TruncateChangeLog removes records of change log with sequence numbers up to seq.
+// Incremental dumps since removed records are not possible after truncation.
+func (s *Storage) TruncateChangeLog(seq uint64) error {
+	_, err := s.RemoveByQueryChunked(NewQuery(tabChangeLog).Until(Inclusive, seq), nil)
+	return err
+}
+
+func (s *Storage) initChangeLog() {
+	if ok, _ := s.db.Has(changeLogSeqKey, nil); ok {
+		atomic.StoreInt32(&s.changeLog, 1)
+	} else {
+		atomic.StoreInt32(&s.changeLog, 0)
+	}
+}
+
+// writeChangeLog writes tracked changes of transaction to change log
+func (t *Transaction) writeChangeLog() {
+	var seq, seq0 uint64
+	t.GetVar(changeLogSeqKey, &seq)
+	seq0 = seq
+	logPrefix := Key(tabChangeLog)
+	written := map[string]bool{}
+	for _, c := range t.changes {
+		if bytes.HasPrefix(c.Key, logPrefix) || written[string(c.Key)] {
+			continue
+		}
+		written[string(c.Key)] = true
+		seq++
+		if err := t.tr.Put(Key(tabChangeLog, seq), c.Key, t.WriteOptions); err != nil {
+			t.Fail(err)
+		}
+	}
+	if seq == seq0 {
+		return
+	}
+	if err := t.tr.Put(changeLogSeqKey, encodeValue(seq), t.WriteOptions); err != nil {
+		t.Fail(err)
+	}
+	// remove records out of retention
+	var retention uint64
+	if t.GetVar(changeLogRetentionKey, &retention); retention == 0 || seq <= retention {
+		return
+	}
+	from := uint64(1)
+	if seq0 > retention {
+		from = seq0 - retention + 1
+	}
+	for i := from; i <= seq-retention; i++ {
+		if err := t.tr.Delete(Key(tabChangeLog, i), t.WriteOptions); err != nil {
+			t.Fail(err)
+		}
+	}
+}
+
+// changedKeys returns keys changed after sequence number since and up to seq (excluding duplicates)
+func (c *context) changedKeys(since, seq uint64, fn func(key []byte) error) error {
+	if since >= seq {
+		return nil
+	}
+	iter := c.qCtx.NewIterator(&util.Range{Start: Key(tabChangeLog, since+1), Limit: Key(tabChangeLog, seq+1)}, c.ReadOptions)
+	defer iter.Release()
+
+	seen := map[string]bool{}
+	for first := true; iter.Next(); first = false {
+		if first && !bytes.Equal(iter.Key(), Key(tabChangeLog, since+1)) {
+			return ErrChangeLogTruncated
+		}
+		if key := iter.Value(); !seen[string(key)] {
+			seen[string(key)] = true
+			if err := fn(append([]byte{}, key...)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if len(seen) == 0 {
+		return ErrChangeLogTruncated
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package goldb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_EnableChangeLog(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 10)
+
+	err := store.EnableChangeLog(0)
+	putTestValues(store, 3)
+	store.Exec(func(tr *Transaction) {
+		tr.Delete(Key(TestTable, "Key", 5))
+	})
+	seq, _ := store.ChangeLogSeq()
+
+	assert.NoError(t, err)
+	assert.True(t, store.ChangeLogEnabled())
+	assert.EqualValues(t, 4, seq)
+}
+
+func TestStorage_EnableChangeLog_reopen(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.EnableChangeLog(0)
+
+	store.Close()
+	store.Open()
+
+	assert.True(t, store.ChangeLogEnabled())
+}
+
+func TestStorage_TruncateChangeLog(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.EnableChangeLog(0)
+	putTestValues(store, 10)
+
+	err := store.TruncateChangeLog(5)
+	n, _ := store.GetNumRows(NewQuery(tabChangeLog))
+	seq, _ := store.ChangeLogSeq()
+
+	assert.NoError(t, err)
+	assert.EqualValues(t, 5, n)
+	assert.EqualValues(t, 10, seq)
+}
+
+func TestStorage_EnableChangeLog_retention(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.EnableChangeLog(5)
+	putTestValues(store, 10)
+	putTestValues(store, 2)
+	n1, _ := store.GetNumRows(NewQuery(tabChangeLog))
+
+	err := store.EnableChangeLog(3)
+	n2, _ := store.GetNumRows(NewQuery(tabChangeLog))
+	seq, _ := store.ChangeLogSeq()
+	errTruncated := store.changedKeys(0, seq, func([]byte) error { return nil })
+
+	assert.NoError(t, err)
+	assert.EqualValues(t, 5, n1)
+	assert.EqualValues(t, 3, n2)
+	assert.EqualValues(t, 12, seq)
+	assert.Equal(t, ErrChangeLogTruncated, errTruncated)
+}
+
+func TestStorage_Dump_excludesChangeLog(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.EnableChangeLog(0)
+	putTestValues(store, 10)
+
+	store.Dump(store.dir+".dump", nil)
+	info, err := VerifyDump(store.dir + ".dump")
+
+	assert.NoError(t, err)
+	assert.EqualValues(t, 11, info.NumRecords) // 10 records and meta-record
+}
@@ -1,7 +1,7 @@
 package goldb
 
 import (
-	"bytes"
+	gocontext "context"
 	"errors"
 	"math/big"
 	"sync"
@@ -16,7 +16,9 @@ import (
 // Context is context of reading data via get or fetch-methods.
 // Context is implemented by Transaction and Storage
 type context struct {
+	ctx          gocontext.Context // context of transaction (nil for storage)
 	qCtx         queryContext
+	fTTL         *ttlEntities // entities having keys with ttl
 	fPanicOnErr  bool
 	rmx          sync.RWMutex
 	ReadOptions  *opt.ReadOptions
@@ -24,6 +26,19 @@ type context struct {
 	countReads   int64
 }
 
+// Reader is context of reading data. Reader is implemented by Storage and Transaction
+type Reader interface {
+	Get(key []byte) ([]byte, error)
+	Fetch(q *Query, fnRecord func(rec Record) error) error
+	GetNumRows(q *Query) (uint64, error)
+}
+
+// Writer is context of writing data. Writer is implemented by Storage and Transaction
+type Writer interface {
+	Put(key, data []byte) error
+	Delete(key []byte) error
+}
+
 type queryContext interface {
 	Get(key []byte, ro *opt.ReadOptions) (value []byte, err error)
 	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
@@ -42,6 +57,18 @@ func (c *context) Get(key []byte) ([]byte, error) {
 	c.rmx.RLock()
 	defer c.rmx.RUnlock()
 
+	data, err := c.qCtx.Get(key, c.ReadOptions)
+	if err == leveldb.ErrNotFound || (err == nil && c.expired(key)) {
+		return nil, nil
+	}
+	if err != nil && c.fPanicOnErr {
+		panic(err)
+	}
+	return data, err
+}
+
+// getRaw returns data by key ignoring ttl of key (nil when key does not exist)
+func (c *context) getRaw(key []byte) ([]byte, error) {
 	data, err := c.qCtx.Get(key, c.ReadOptions)
 	if err == leveldb.ErrNotFound {
 		return nil, nil
@@ -124,6 +151,11 @@ func (c *context) Fetch(q *Query, fnRecord func(rec Record) error) error {
 	return c.execute(q, fnRecord)
 }
 
+// FetchCtx fetches data by query. Fetching is stopped with error ctx.Err() when ctx is done
+func (c *context) FetchCtx(ctx gocontext.Context, q *Query, fnRecord func(rec Record) error) error {
+	return c.executeCtx(ctx, q, fnRecord)
+}
+
 // FetchID fetches uint64-ID by query
 func (c *context) FetchID(q *Query, fnRow func(id uint64) error) error {
 	return c.execute(q, func(rec Record) error {
@@ -172,68 +204,29 @@ func (c *context) LastRowID(tableID Entity) (rowID uint64, err error) {
 }
 
 // ------ private ------
-var tail1024 = bytes.Repeat([]byte{255}, 1024)
-
-func (c *context) execute(q *Query, fnRow func(rec Record) error) (err error) {
-	q.NumRows = 0
-	pfx := q.filter
-	pfxLen := len(pfx)
-	start := append(pfx, q.offset...)
-	nStart := len(start)
-	limit := q.limit
-	if limit < 0 {
-		limit = 1e15
-	}
-	skipFirst := len(q.offset) > 0
-
-	var iter iterator.Iterator
-	var iterNext func() bool
-	var fnRecordFilter = q.fnFilter
-
-	c.rmx.RLock()
-	defer c.rmx.RUnlock()
-
-	if !q.desc { // ask
-		iter = c.qCtx.NewIterator(&util.Range{Start: start}, nil)
-		iterNext = func() bool { return iter.Next() }
+func (c *context) execute(q *Query, fnRow func(rec Record) error) error {
+	return c.executeCtx(c.ctx, q, fnRow)
+}
 
-	} else { // desc
-		iter = c.qCtx.NewIterator(nil, nil)
-		iter.Seek(append(start, tail1024...))
-		iterNext = func() bool { return iter.Prev() }
-	}
+func (c *context) executeCtx(ctx gocontext.Context, q *Query, fnRow func(rec Record) error) (err error) {
+	cur := c.CursorCtx(ctx, q)
+	cur.fCountRows = false // count the record only when fnRow accepted it
 
 	defer func() {
 		if r, _ := recover().(error); r != nil && r != Break {
 			err = r
 		}
-		iter.Release()
-		if err == nil {
-			err = iter.Error()
+		if e := cur.Close(); err == nil {
+			err = e
 		}
 		if err != nil && c.fPanicOnErr {
 			panic(err)
 		}
 	}()
 
-	for limit > 0 && iterNext() {
-		key := iter.Key()
-		if !bytes.HasPrefix(key, pfx) {
-			break
-		}
-		if skipFirst { // skip first record if record.key == startOffset
-			if len(key) >= nStart && bytes.Equal(key[:nStart], start) {
-				continue
-			}
-			skipFirst = false
-		}
-		if fnRecordFilter != nil && !fnRecordFilter(Record{key, iter.Value()}) {
-			continue
-		}
-		q.offset = key[pfxLen:]
-		limit--
+	for cur.Next() {
 		if fnRow != nil {
-			if err = fnRow(Record{key, iter.Value()}); err != nil {
+			if err = fnRow(cur.Record()); err != nil {
 				if err == Break {
 					err = nil
 				}
@@ -0,0 +1,193 @@
+package goldb
+
+import (
+	"bytes"
+	gocontext "context"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Cursor is pull-based iterator over results of query.
+// Cursor reads records by chunks and holds no locks between calls of Next,
+// so records may be read and written by the same goroutine while iterating.
+// Cursor of Storage reads from consistent snapshot of storage, so it must be closed after use;
+// Vacuum, Truncate and Restore close database of storage, then reading of cursors opened before fails with leveldb.ErrClosed.
+//
+//	cur := store.Cursor(q)
+//	defer cur.Close()
+//	for cur.Next() {
+//		rec := cur.Record()
+//		...
+//	}
+//	err := cur.Err()
+type Cursor struct {
+	c       *context
+	src     queryContext      // source of records (snapshot of storage or reading context)
+	snap    *leveldb.Snapshot // snapshot of storage; is released by Close
+	ctx     gocontext.Context
+	q       *Query
+	rng     util.Range  // range of keys that are not read yet
+	buf     []cursorRow // records of read chunk
+	eof     bool        // all records of range are read
+	closed  bool
+	limit   int64
+	rec     Record
+	err     error
+	started time.Time
+
+	fCountRows bool // count returned records in q.NumRows
+}
+
+type cursorRow struct {
+	key []byte // key of scanned row (index row for index query)
+	rec Record
+}
+
+// cursorChunkSize is max number of records read by cursor at once
+const cursorChunkSize = 256
+
+// Cursor opens cursor over results of query
+func (c *context) Cursor(q *Query) *Cursor {
+	return c.CursorCtx(c.ctx, q)
+}
+
+// CursorCtx opens cursor over results of query. Iteration is stopped with error ctx.Err() when ctx is done
+func (c *context) CursorCtx(ctx gocontext.Context, q *Query) *Cursor {
+	q.NumRows, q.RowsScanned, q.BytesRead, q.Seeks = 0, 0, 0, 0
+	cur := &Cursor{
+		c:       c,
+		ctx:     ctx,
+		q:       q,
+		rng:     q.iterRange(),
+		limit:   q.limit,
+		started: time.Now(),
+
+		fCountRows: true,
+	}
+	if cur.limit < 0 {
+		cur.limit = 1e15
+	}
+
+	c.rmx.RLock()
+	defer c.rmx.RUnlock()
+
+	cur.src = c.qCtx
+	if db, ok := c.qCtx.(*leveldb.DB); ok { // storage
+		if cur.snap, cur.err = db.GetSnapshot(); cur.err == nil {
+			cur.src = cur.snap
+		}
+	}
+	return cur
+}
+
+// Next moves cursor to next record. Returns false when there are no more records or error occurred
+func (cur *Cursor) Next() bool {
+	cur.rec = Record{}
+	for !cur.closed && cur.err == nil && cur.limit > 0 {
+		if cur.ctx != nil {
+			if cur.err = cur.ctx.Err(); cur.err != nil {
+				return false
+			}
+		}
+		if len(cur.buf) == 0 {
+			if cur.eof {
+				return false
+			}
+			cur.readChunk()
+			continue
+		}
+		row := cur.buf[0]
+		cur.buf = cur.buf[1:]
+		if cur.q.fnFilter != nil && !cur.q.fnFilter(row.rec) {
+			continue
+		}
+		cur.q.offset = row.key[len(cur.q.filter):]
+		if cur.fCountRows {
+			cur.q.NumRows++
+		}
+		cur.limit--
+		cur.rec = row.rec
+		return true
+	}
+	return false
+}
+
+// readChunk reads next chunk of records of range.
+// Read-lock of the context is held only while reading (it is not held while records are processed)
+func (cur *Cursor) readChunk() {
+	cur.c.rmx.RLock()
+	defer cur.c.rmx.RUnlock()
+
+	n := cursorChunkSize
+	if cur.q.fnFilter == nil && cur.limit < int64(n) {
+		n = int(cur.limit)
+	}
+	iter := cur.src.NewIterator(&cur.rng, nil)
+	defer iter.Release()
+	cur.q.Seeks++
+
+	ok, next := iter.First(), iter.Next
+	if cur.q.desc {
+		ok, next = iter.Last(), iter.Prev
+	}
+	for ; ok && len(cur.buf) < n; ok = next() {
+		key := iter.Key()
+		if !bytes.HasPrefix(key, cur.q.filter) {
+			cur.eof = true
+			break
+		}
+		key = append([]byte{}, key...)
+		if !cur.q.desc {
+			cur.rng.Start = append(key[:len(key):len(key)], 0) // next key after the key
+		} else {
+			cur.rng.Limit = key
+		}
+		rec := Record{key, append([]byte{}, iter.Value()...)}
+		cur.q.RowsScanned++
+		cur.q.BytesRead += uint64(len(rec.Key) + len(rec.Value))
+		if cur.q.index {
+			cur.q.Seeks++
+			if rec, cur.err = cur.c.primaryRecord(cur.src, rec); cur.err != nil {
+				return
+			} else if rec.Value == nil { // stale index row
+				continue
+			}
+			cur.q.BytesRead += uint64(len(rec.Value))
+		}
+		if cur.c.expiredIn(cur.src, rec.Key) {
+			continue
+		}
+		cur.buf = append(cur.buf, cursorRow{key, rec})
+	}
+	if !ok {
+		cur.eof = true
+	}
+	cur.err = iter.Error()
+}
+
+// Record returns current record. The record is valid until next call of Next
+func (cur *Cursor) Record() Record {
+	return cur.rec
+}
+
+// Err returns error of iteration
+func (cur *Cursor) Err() error {
+	return cur.err
+}
+
+// Close releases cursor and snapshot of storage
+func (cur *Cursor) Close() error {
+	if !cur.closed {
+		cur.closed = true
+		cur.buf = nil
+		if cur.snap != nil {
+			cur.snap.Release()
+		}
+		cur.q.offset = append([]byte{}, cur.q.offset...)
+		cur.q.Elapsed = time.Since(cur.started)
+	}
+	cur.rec = Record{}
+	return cur.err
+}
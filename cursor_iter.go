@@ -0,0 +1,23 @@
+//go:build go1.23
+
+package goldb
+
+import "iter"
+
+// Records returns iterator over results of query.
+// An iteration error is yielded as the last pair with empty record
+func (c *context) Records(q *Query) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		cur := c.Cursor(q)
+		defer cur.Close()
+
+		for cur.Next() {
+			if !yield(cur.Record(), nil) {
+				return
+			}
+		}
+		if err := cur.Close(); err != nil {
+			yield(Record{}, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+//go:build go1.23
+
+package goldb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext_Records(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, "A", 1), "Alice")
+		tr.PutVar(Key(TestTable, "B", 2), "Bob")
+		tr.PutVar(Key(TestTable, "C", 3), "Cat")
+	})
+
+	var names []string
+	for rec, err := range store.Records(NewQuery(TestTable)) {
+		assert.NoError(t, err)
+		if names = append(names, rec.ValueStr()); len(names) == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"Alice", "Bob"}, names)
+}
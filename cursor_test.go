@@ -0,0 +1,93 @@
+package goldb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestContext_Cursor(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, "A", 1), "Alice")
+		tr.PutVar(Key(TestTable, "B", 2), "Bob")
+		tr.PutVar(Key(TestTable, "C", 3), "Cat")
+	})
+
+	q := NewQuery(TestTable).OrderDesc().Limit(2)
+	cur := store.Cursor(q)
+	var names []string
+	for cur.Next() {
+		names = append(names, cur.Record().ValueStr())
+	}
+	err := cur.Close()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Cat", "Bob"}, names)
+	assert.Equal(t, 2, int(q.NumRows))
+	assert.False(t, cur.Next())
+
+	// continue from current offset
+	cur = store.Cursor(q)
+	defer cur.Close()
+	assert.True(t, cur.Next())
+	assert.Equal(t, "Alice", cur.Record().ValueStr())
+	assert.False(t, cur.Next())
+	assert.NoError(t, cur.Err())
+}
+
+func TestTransaction_Cursor(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	var names []string
+	err := store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, "A", 1), "Alice")
+		tr.PutVar(Key(TestTable, "B", 2), "Bob")
+
+		cur := tr.Cursor(NewQuery(TestTable))
+		defer cur.Close()
+		for cur.Next() {
+			names = append(names, cur.Record().ValueStr())
+		}
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Bob"}, names)
+}
+
+func TestStorage_Cursor_pendingVacuum(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 1000)
+
+	cur := store.Cursor(NewQuery(TestTable))
+	defer cur.Close()
+	n := 0
+	for ; cur.Next() && n < 10; n++ {
+		store.Get(cur.Record().Key) // nested read
+	}
+	vacuumed := make(chan error)
+	go func() {
+		vacuumed <- store.Vacuum()
+	}()
+	var err error
+	select {
+	case err = <-vacuumed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("vacuum waits for open cursor")
+	}
+	for cur.Next() {
+		n++
+	}
+	v, errGet := store.GetStr(Key(TestTable, "Key", 1))
+
+	assert.NoError(t, err)
+	assert.NoError(t, errGet)
+	assert.Equal(t, "Value 1", v)
+	assert.Equal(t, leveldb.ErrClosed, cur.Err()) // cursor is invalidated by vacuum
+	assert.True(t, n < 1000)
+}
@@ -1,37 +1,92 @@
 package goldb
 
 import (
+	"bytes"
 	"compress/flate"
+	gocontext "context"
+	"errors"
+	"io"
 	"os"
+	"sync/atomic"
 
-	"github.com/denisskin/bin"
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 type DumpOptions struct {
 	Filter           *Query
-	CompressionLevel int // 0-9 (0 - no compression; 9 - by default)
+	CompressionLevel int    // 0-9 (0 - no compression; 9 - by default); is used by flate codec
+	Codec            string // compression codec (CodecFlate by default, CodecNone, CodecSnappy or registered codec)
+
+	Incremental bool   // dump only keys put or deleted after change log sequence Since (change log must be enabled)
+	Since       uint64 // sequence number of change log of previous dump (value of Seq after the previous dump)
+	Seq         uint64 // result: sequence number of change log at the moment of dump
 }
 
+// tabDumpMeta is system entity of meta-records of dumps
+const tabDumpMeta Entity = 0x7ffffffb
+
+var (
+	dumpSeqKey       = Key(tabDumpMeta, "seq")   // sequence number of change log of dump
+	dumpSinceKey     = Key(tabDumpMeta, "since") // sequence number of change log of base dump (incremental dumps only)
+	dumpTombstoneKey = Key(tabDumpMeta, "del")   // prefix of deleted keys (incremental dumps only)
+)
+
+var ErrDumpChain = errors.New("goldb: incremental dump does not follow the restored data")
+
 func (s *Storage) Dump(filepath string, options *DumpOptions) (err error) {
-	op := DumpOptions{
-		CompressionLevel: flate.DefaultCompression,
+	return s.DumpCtx(gocontext.Background(), filepath, options)
+}
+
+// DumpCtx dumps data of storage to file. Dumping is interrupted with error ctx.Err() when ctx is done.
+// The file is synced after dumping; partially written file is removed on error
+func (s *Storage) DumpCtx(ctx gocontext.Context, filepath string, options *DumpOptions) (err error) {
+	if _, err = s.dumpOptions(options); err != nil {
+		return
 	}
-	if options != nil {
-		op = *options
+	file, err := os.Create(filepath)
+	if err != nil {
+		return
 	}
+	defer func() {
+		if errClose := file.Close(); err == nil {
+			err = errClose
+		}
+		if err != nil {
+			os.Remove(filepath)
+		}
+	}()
+	if err = s.DumpToCtx(ctx, file, options); err == nil {
+		err = file.Sync()
+	}
+	return
+}
 
-	file, err := os.Create(filepath)
+// DumpTo writes dump of storage to writer.
+// The writer is synced periodically when it implements method Sync() error (as *os.File).
+func (s *Storage) DumpTo(w io.Writer, options *DumpOptions) error {
+	return s.DumpToCtx(gocontext.Background(), w, options)
+}
+
+// DumpToCtx writes dump of storage to writer. Dumping is interrupted with error ctx.Err() when ctx is done
+func (s *Storage) DumpToCtx(ctx gocontext.Context, wr io.Writer, options *DumpOptions) (err error) {
+	op, err := s.dumpOptions(options)
 	if err != nil {
 		return
 	}
-	defer file.Close()
 
-	fl, err := flate.NewWriter(file, op.CompressionLevel)
+	snap, err := s.Snapshot()
 	if err != nil {
 		return
 	}
-	defer fl.Close()
-	w := bin.NewWriter(fl)
+	defer snap.Release()
+
+	var seq uint64
+	if _, err = snap.GetVar(changeLogSeqKey, &seq); err != nil {
+		return
+	}
+	if op.Incremental && op.Since > seq {
+		return ErrDumpChain
+	}
 
 	q := op.Filter
 	if q == nil {
@@ -39,70 +94,331 @@ func (s *Storage) Dump(filepath string, options *DumpOptions) (err error) {
 		q.filter = nil // all keys
 	}
 
+	w, err := newDumpWriter(wr, q.filter, op.Codec, op.CompressionLevel)
+	if err != nil {
+		return
+	}
+	syncer, _ := wr.(interface{ Sync() error })
+
 	const SyncBatchSize = 32 * 1024 * 1024 // 32 MiB
 	var nextSyncVol = int64(SyncBatchSize)
-	err = s.Fetch(q, func(rec Record) error {
-		w.WriteVar(rec.Key)
-		w.WriteVar(rec.Value)
-		if w.CntWritten > nextSyncVol {
+	writeRecord := func(key, value []byte) error {
+		if err := w.writeRecord(key, value); err != nil {
+			return err
+		}
+		if w.w.CntWritten > nextSyncVol && syncer != nil {
 			nextSyncVol += SyncBatchSize
-			if err := file.Sync(); err != nil {
+			if err := syncer.Sync(); err != nil {
 				return err
 			}
 		}
-		return w.Error()
-	})
+		return nil
+	}
 
-	w.WriteVar([]byte{}) // EOF - null-key
+	// meta-records
+	writeRecord(dumpSeqKey, encodeValue(seq))
+	if op.Incremental {
+		writeRecord(dumpSinceKey, encodeValue(op.Since))
+	}
+
+	if op.Incremental {
+		err = snap.changedKeys(op.Since, seq, func(key []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !bytes.HasPrefix(key, q.filter) {
+				return nil
+			}
+			value, err := snap.qCtx.Get(key, snap.ReadOptions)
+			if err == leveldb.ErrNotFound {
+				return writeRecord(concat(dumpTombstoneKey, key), nil)
+			} else if err != nil {
+				return err
+			}
+			return writeRecord(key, value)
+		})
+	} else {
+		err = snap.FetchCtx(ctx, q, func(rec Record) error {
+			if isLocalKey(rec.Key) { // change log and dump meta-records are not dumped
+				return nil
+			}
+			return writeRecord(rec.Key, rec.Value)
+		})
+	}
+
+	if err != nil {
+		return // dump without trailer is invalid
+	}
+	if err = w.Close(); err == nil && options != nil {
+		options.Seq = seq
+	}
+	return
+}
+
+// dumpOptions returns dump options with defaults; returns error when the options are invalid
+func (s *Storage) dumpOptions(options *DumpOptions) (op DumpOptions, err error) {
+	op = DumpOptions{
+		CompressionLevel: flate.DefaultCompression,
+	}
+	if options != nil {
+		op = *options
+	}
+	if op.Codec == "" {
+		op.Codec = CodecFlate
+	}
+	if op.Incremental && !s.ChangeLogEnabled() {
+		return op, ErrChangeLogDisabled
+	}
+	c, err := dumpCodec(op.Codec)
+	if err != nil {
+		return
+	}
+	cw, err := c.NewWriter(io.Discard, op.CompressionLevel) // checks compression level
+	if err == nil {
+		err = cw.Close()
+	}
 	return
 }
 
+// ConflictPolicy defines how merge restore treats keys that already exist in storage
+type ConflictPolicy int
+
+const (
+	ConflictOverwrite ConflictPolicy = iota // dumped value replaces existing value
+	ConflictKeep                            // existing value is kept
+	ConflictResolve                         // value is chosen by RestoreOptions.Resolve
+)
+
+type RestoreOptions struct {
+	Merge    bool // import dump into live storage without truncation of existing data
+	Conflict ConflictPolicy
+
+	// Resolve returns value to store for key existing in storage (ConflictResolve policy).
+	// dumped is nil for key deleted by incremental dump; nil result deletes the key.
+	Resolve func(key, existing, dumped []byte) []byte
+
+	// RewriteKey returns new key for dumped key; nil result skips the record.
+	// Keys of system records (ttl) are not rewritten; sequences are not restored when RewriteKey is set.
+	RewriteKey func(key []byte) []byte
+}
+
+var errRestoreResolve = errors.New("goldb: RestoreOptions.Resolve is not defined")
+
 func (s *Storage) Restore(filepath string) (err error) {
+	return s.RestoreCtx(gocontext.Background(), filepath)
+}
+
+// RestoreChain restores data of storage from full dump followed by chain of incremental dumps
+func (s *Storage) RestoreChain(filepaths ...string) error {
+	for _, path := range filepaths {
+		if err := s.Restore(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreCtx restores data of storage from dump-file.
+// A full dump replaces all data of storage; an incremental dump is applied to data restored from the previous dump.
+// Restoring is interrupted with error ctx.Err() when ctx is done; data of storage is not changed then.
+func (s *Storage) RestoreCtx(ctx gocontext.Context, filepath string) (err error) {
+	return s.restoreFile(ctx, filepath, nil)
+}
+
+// RestoreWith restores data of storage from dump-file with options.
+// In merge mode the dump is imported into live storage by transactions with the conflict policy.
+// Merge is committed by chunks of records, so interrupted merge leaves already committed chunks in storage.
+func (s *Storage) RestoreWith(filepath string, options *RestoreOptions) (err error) {
+	return s.restoreFile(gocontext.Background(), filepath, options)
+}
+
+func (s *Storage) restoreFile(ctx gocontext.Context, filepath string, options *RestoreOptions) (err error) {
 	file, err := os.Open(filepath)
 	if err != nil {
 		return
 	}
 	defer file.Close()
 
-	fl := flate.NewReader(file)
-	defer fl.Close()
-	r := bin.NewReader(fl)
+	// verify dump before any changes of storage
+	if _, err = VerifyDump(filepath); err != nil {
+		return
+	}
+	return s.RestoreFromCtx(ctx, file, options)
+}
+
+// RestoreFrom restores data of storage from dump read from reader
+func (s *Storage) RestoreFrom(r io.Reader, options *RestoreOptions) error {
+	return s.RestoreFromCtx(gocontext.Background(), r, options)
+}
+
+// RestoreFromCtx restores data of storage from dump read from reader.
+// Full dump is restored to new database that replaces database of storage after successful reading,
+// and incremental dump is applied by one transaction, so data of storage is not changed
+// when the stream is corrupted or ctx is done (except merge mode, see RestoreWith).
+// Returns ErrSnapshotsOpen for full dump when storage has open snapshots.
+func (s *Storage) RestoreFromCtx(ctx gocontext.Context, r io.Reader, options *RestoreOptions) error {
+	d, err := newDumpReader(r)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return s.restore(ctx, d, options)
+}
+
+type dumpHeader struct {
+	seq, since          uint64
+	hasSeq, incremental bool
+}
+
+// readDumpHeader reads meta-records of dump. Returns the first data record
+func readDumpHeader(r *dumpReader) (h dumpHeader, key, val []byte, err error) {
+	if key, val, err = r.readRecord(); err != nil {
+		return
+	}
+	if bytes.Equal(key, dumpSeqKey) {
+		if err = decodeValue(val, &h.seq); err != nil {
+			return
+		}
+		h.hasSeq = true
+		if key, val, err = r.readRecord(); err != nil {
+			return
+		}
+	}
+	if bytes.Equal(key, dumpSinceKey) {
+		if err = decodeValue(val, &h.since); err != nil {
+			return
+		}
+		h.incremental = true
+		key, val, err = r.readRecord()
+	}
+	return
+}
+
+func (s *Storage) restore(ctx gocontext.Context, r *dumpReader, options *RestoreOptions) (err error) {
+	var op RestoreOptions
+	if options != nil {
+		op = *options
+	}
+	if op.Conflict == ConflictResolve && op.Resolve == nil {
+		return errRestoreResolve
+	}
+	h, key, val, err := readDumpHeader(r)
+	if err != nil {
+		return
+	}
+	if op.Merge {
+		return s.mergeRestore(ctx, r, key, val, &op)
+	}
 
 	s.mx.Lock()
 	defer s.mx.Unlock()
 
-	// truncate db
-	if err = s.Drop(); err != nil {
+	if !h.incremental {
+		return s.restoreFull(ctx, r, h, key, val, &op)
+	}
+	var curSeq uint64
+	if ok, err := s.GetVar(dumpSeqKey, &curSeq); err != nil {
+		return err
+	} else if !ok || curSeq != h.since {
+		return ErrDumpChain
+	}
+	// incremental dump is applied by one transaction, so it is discarded entirely on error
+	tr, err := s.db.OpenTransaction()
+	if err != nil {
 		return
 	}
-	if err = s.Open(); err != nil {
+	if err = writeDumpRecords(ctx, tr, r, h, key, val, &op); err != nil {
+		tr.Discard()
 		return
 	}
-	//
-	tr, err := s.db.OpenTransaction()
+	if err = tr.Commit(); err != nil {
+		return
+	}
+	s.initTTL()
+	s.initChangeLog()
+	return
+}
+
+// restoreFull restores full dump to new database that replaces database of storage
+func (s *Storage) restoreFull(ctx gocontext.Context, r *dumpReader, h dumpHeader, key, val []byte, op *RestoreOptions) (err error) {
+	if atomic.LoadInt64(&s.cntSnapshots) > 0 { // fail before restoring of data
+		return ErrSnapshotsOpen
+	}
+	tmpDir := s.dir + ".restore"
+	os.RemoveAll(tmpDir)
+	defer os.RemoveAll(tmpDir)
+
+	db, err := leveldb.OpenFile(tmpDir, s.op)
+	if err != nil {
+		return
+	}
+	tr, err := db.OpenTransaction()
+	if err == nil {
+		if err = writeDumpRecords(ctx, tr, r, h, key, val, op); err == nil {
+			err = tr.Commit()
+		} else {
+			tr.Discard()
+		}
+	}
+	if e := db.Close(); err == nil {
+		err = e
+	}
 	if err != nil {
 		return
 	}
-	var key, val []byte
-	for i := 0; ; {
-		if key, err = r.ReadBytes(); err != nil {
+	return s.replaceDB(tmpDir)
+}
+
+// writeDumpRecords writes records of dump to transaction
+func writeDumpRecords(ctx gocontext.Context, tr *leveldb.Transaction, r *dumpReader, h dumpHeader, key, val []byte, op *RestoreOptions) (err error) {
+	for len(key) != 0 { // empty key - EOF
+		if err = ctx.Err(); err != nil {
 			return
-		} else if len(key) == 0 { // EOF
-			break
 		}
-		if val, err = r.ReadBytes(); err != nil {
+		switch c := dumpChange(key, val, h.incremental, op); {
+		case c.Key == nil: // record is skipped
+		case c.Op == OpDelete:
+			err = tr.Delete(c.Key, nil)
+		default:
+			err = tr.Put(c.Key, c.Value, nil)
+		}
+		if err != nil {
 			return
 		}
-		tr.Put(key, val, nil)
-
-		if i++; i%10000 == 0 {
-			if err = tr.Commit(); err != nil {
-				return
-			} else if tr, err = s.db.OpenTransaction(); err != nil {
-				return
-			}
+		if key, val, err = r.readRecord(); err != nil {
+			return
 		}
 	}
-	err = tr.Commit()
+	if h.hasSeq {
+		err = tr.Put(dumpSeqKey, encodeValue(h.seq), nil)
+	}
 	return
 }
+
+// isLocalKey returns true for keys of change log and dump meta-records (local data of the dumped storage)
+func isLocalKey(key []byte) bool {
+	if bytes.HasPrefix(key, dumpTombstoneKey) {
+		return false
+	}
+	return bytes.HasPrefix(key, Key(tabChangeLog)) || bytes.HasPrefix(key, Key(tabDumpMeta)) || bytes.HasPrefix(key, Key(tabChangeLogMeta))
+}
+
+// dumpChange returns change of storage by record of dump. Returns change with nil key for skipped record
+func dumpChange(key, val []byte, incremental bool, op *RestoreOptions) Change {
+	c := Change{OpPut, key, val}
+	if incremental && bytes.HasPrefix(key, dumpTombstoneKey) {
+		c = Change{OpDelete, key[len(dumpTombstoneKey):], nil}
+	}
+	if op.RewriteKey != nil && bytes.HasPrefix(c.Key, Key(tabSequences)) { // sequences of rewritten entities are unknown
+		c.Key = nil
+	} else if op.RewriteKey != nil && !isSystemKey(c.Key) {
+		c.Key = op.RewriteKey(c.Key)
+	}
+	return c
+}
+
+// isSystemKey returns true for keys of reserved system entities
+func isSystemKey(key []byte) bool {
+	tab, err := decodeUint(key)
+	return err == nil && Entity(tab) >= minSystemEntity
+}
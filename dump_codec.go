@@ -0,0 +1,79 @@
+package goldb
+
+import (
+	"compress/flate"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+)
+
+// Compression codecs of dumps
+const (
+	CodecFlate  = "flate" // by default
+	CodecNone   = "none"
+	CodecSnappy = "snappy"
+)
+
+// DumpCodec is compression codec of dumps
+type DumpCodec struct {
+	NewWriter func(w io.Writer, level int) (io.WriteCloser, error)
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var dumpCodecs = struct {
+	sync.RWMutex
+	m map[string]DumpCodec
+}{m: map[string]DumpCodec{
+	CodecFlate: {
+		NewWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+			return flate.NewWriter(w, level)
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return flate.NewReader(r), nil
+		},
+	},
+	CodecNone: {
+		NewWriter: func(w io.Writer, _ int) (io.WriteCloser, error) {
+			return nopWriteCloser{w}, nil
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(r), nil
+		},
+	},
+	CodecSnappy: {
+		NewWriter: func(w io.Writer, _ int) (io.WriteCloser, error) {
+			return snappy.NewBufferedWriter(w), nil
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(snappy.NewReader(r)), nil
+		},
+	},
+}}
+
+// RegisterDumpCodec registers compression codec of dumps (for example, zstd).
+// The codec is selected by DumpOptions.Codec and detected by name in header of dump on restore.
+func RegisterDumpCodec(name string, codec DumpCodec) {
+	if len(name) == 0 || len(name) > 255 {
+		panic("goldb: invalid name of dump codec")
+	}
+	dumpCodecs.Lock()
+	defer dumpCodecs.Unlock()
+	dumpCodecs.m[name] = codec
+}
+
+func dumpCodec(name string) (codec DumpCodec, err error) {
+	dumpCodecs.RLock()
+	defer dumpCodecs.RUnlock()
+	codec, ok := dumpCodecs.m[name]
+	if !ok {
+		err = ErrDumpCodec
+	}
+	return
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
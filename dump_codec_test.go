@@ -0,0 +1,61 @@
+package goldb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	RegisterDumpCodec("test-gzip", DumpCodec{
+		NewWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	})
+}
+
+func TestStorage_Dump_codecs(t *testing.T) {
+	for _, codec := range []string{"", CodecFlate, CodecNone, CodecSnappy, "test-gzip"} {
+		store1 := newTestStorage()
+		store2 := newTestStorage()
+		putTestValues(store1, 1000)
+		buf := bytes.NewBuffer(nil)
+
+		err1 := store1.DumpTo(buf, &DumpOptions{Codec: codec, CompressionLevel: 9})
+		err2 := store2.RestoreFrom(buf, nil)
+
+		assert.NoError(t, err1)
+		assert.NoError(t, err2)
+		n, _ := store2.GetNumRows(NewQuery(TestTable))
+		assert.EqualValues(t, 1000, n)
+		store1.Drop()
+		store2.Drop()
+	}
+}
+
+func TestVerifyDump_codec(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 10)
+	store.Dump(store.dir+".dump", &DumpOptions{Codec: CodecSnappy})
+
+	info, err := VerifyDump(store.dir + ".dump")
+
+	assert.NoError(t, err)
+	assert.Equal(t, CodecSnappy, info.Codec)
+}
+
+func TestStorage_Dump_unknownCodec(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	err := store.DumpTo(bytes.NewBuffer(nil), &DumpOptions{Codec: "unknown"})
+
+	assert.Equal(t, ErrDumpCodec, err)
+}
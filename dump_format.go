@@ -0,0 +1,355 @@
+package goldb
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+
+	"github.com/denisskin/bin"
+)
+
+// Dump format (version 2):
+//
+//	header:  magic "GOLDBDMP" | version (1 byte) | created (unix nano, 8 bytes) |
+//	         filter (4 bytes length + data) | codec name (1 byte length + data) | crc32 of header (4 bytes)
+//	blocks:  compressed stream of records, framed by blocks: length (4 bytes) | crc32 (4 bytes) | data;
+//	         block with zero length is the end of blocks
+//	trailer: number of records (8 bytes) | sha256 of uncompressed stream of records (32 bytes)
+//
+// Legacy dumps (version 1) are flate-compressed streams of records without header and trailer.
+const (
+	dumpMagic         = "GOLDBDMP"
+	dumpVersion       = 2
+	dumpLegacyVersion = 1
+	dumpBlockSize     = 256 * 1024
+)
+
+var (
+	ErrDumpCorrupted       = errors.New("goldb: dump is corrupted")
+	ErrDumpVersion         = errors.New("goldb: unsupported version of dump")
+	ErrDumpCodec           = errors.New("goldb: unsupported compression codec of dump")
+	errDumpRecordsAfterEOF = errors.New("goldb: dump has no more records")
+)
+
+// DumpInfo is description of dump-file
+type DumpInfo struct {
+	Version    int
+	Created    time.Time // zero for legacy dumps
+	Filter     []byte    // prefix of dumped keys (nil - all keys)
+	Codec      string
+	NumRecords uint64 // number of records (including meta-records)
+}
+
+// VerifyDump reads dump-file and validates its checksums and trailer without touching any storage
+func VerifyDump(filepath string) (info DumpInfo, err error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	d, err := newDumpReader(file)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	for {
+		key, _, err := d.readRecord()
+		if err != nil {
+			return d.info, err
+		}
+		if len(key) == 0 { // EOF
+			return d.info, nil
+		}
+	}
+}
+
+// ------------------------------------
+type dumpWriter struct {
+	w      *bin.Writer
+	comp   io.WriteCloser
+	blocks *blockWriter
+	hash   hash.Hash
+	count  uint64
+}
+
+func newDumpWriter(w io.Writer, filter []byte, codec string, level int) (d *dumpWriter, err error) {
+	c, err := dumpCodec(codec)
+	if err != nil {
+		return
+	}
+	h := bytes.NewBufferString(dumpMagic)
+	h.WriteByte(dumpVersion)
+	binary.Write(h, binary.BigEndian, time.Now().UnixNano())
+	binary.Write(h, binary.BigEndian, uint32(len(filter)))
+	h.Write(filter)
+	h.WriteByte(byte(len(codec)))
+	h.WriteString(codec)
+	binary.Write(h, binary.BigEndian, crc32.ChecksumIEEE(h.Bytes()))
+	if _, err = w.Write(h.Bytes()); err != nil {
+		return
+	}
+
+	d = &dumpWriter{
+		blocks: &blockWriter{w: w},
+		hash:   sha256.New(),
+	}
+	if d.comp, err = c.NewWriter(d.blocks, level); err != nil {
+		return
+	}
+	d.w = bin.NewWriter(io.MultiWriter(d.comp, d.hash))
+	return
+}
+
+func (d *dumpWriter) writeRecord(key, value []byte) error {
+	d.w.WriteVar(key)
+	d.w.WriteVar(value)
+	d.count++
+	return d.w.Error()
+}
+
+// Close writes end of records, flushes compressed data and writes trailer
+func (d *dumpWriter) Close() error {
+	d.w.WriteVar([]byte{}) // EOF - null-key
+	if err := d.w.Error(); err != nil {
+		return err
+	}
+	if err := d.comp.Close(); err != nil {
+		return err
+	}
+	if err := d.blocks.Close(); err != nil {
+		return err
+	}
+	var trailer [8]byte
+	binary.BigEndian.PutUint64(trailer[:], d.count)
+	_, err := d.blocks.w.Write(d.hash.Sum(trailer[:]))
+	return err
+}
+
+// ------------------------------------
+type dumpReader struct {
+	info   DumpInfo
+	src    io.Reader
+	blocks *blockReader
+	r      *bin.Reader
+	data   io.Reader // uncompressed stream of records
+	decomp io.ReadCloser
+	hash   hash.Hash
+	eof    bool
+}
+
+func newDumpReader(src io.Reader) (d *dumpReader, err error) {
+	d = &dumpReader{src: src, hash: sha256.New()}
+
+	var magic [len(dumpMagic)]byte
+	n, err := io.ReadFull(src, magic[:])
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	if string(magic[:n]) != dumpMagic { // legacy format
+		d.info.Version = dumpLegacyVersion
+		d.info.Codec = CodecFlate
+		d.decomp = flate.NewReader(io.MultiReader(bytes.NewReader(magic[:n]), src))
+		d.data = d.decomp
+		d.r = bin.NewReader(d.data)
+		return d, nil
+	}
+
+	h := bytes.NewBuffer(magic[:])
+	hr := io.TeeReader(src, h)
+	var ver [1]byte
+	var created int64
+	var filterLen uint32
+	var codecLen [1]byte
+	if _, err = io.ReadFull(hr, ver[:]); err != nil {
+		return nil, dumpReadErr(err)
+	}
+	if ver[0] != dumpVersion {
+		return nil, ErrDumpVersion
+	}
+	if err = binary.Read(hr, binary.BigEndian, &created); err != nil {
+		return nil, dumpReadErr(err)
+	}
+	if err = binary.Read(hr, binary.BigEndian, &filterLen); err != nil {
+		return nil, dumpReadErr(err)
+	}
+	filter := make([]byte, filterLen)
+	if _, err = io.ReadFull(hr, filter); err != nil {
+		return nil, dumpReadErr(err)
+	}
+	if _, err = io.ReadFull(hr, codecLen[:]); err != nil {
+		return nil, dumpReadErr(err)
+	}
+	codec := make([]byte, codecLen[0])
+	if _, err = io.ReadFull(hr, codec); err != nil {
+		return nil, dumpReadErr(err)
+	}
+	checksum := crc32.ChecksumIEEE(h.Bytes())
+	var crc uint32
+	if err = binary.Read(src, binary.BigEndian, &crc); err != nil {
+		return nil, dumpReadErr(err)
+	}
+	if crc != checksum {
+		return nil, ErrDumpCorrupted
+	}
+	d.info = DumpInfo{
+		Version: int(ver[0]),
+		Created: time.Unix(0, created),
+		Codec:   string(codec),
+	}
+	if filterLen > 0 {
+		d.info.Filter = filter
+	}
+	c, err := dumpCodec(d.info.Codec)
+	if err != nil {
+		return nil, err
+	}
+	d.blocks = &blockReader{r: src}
+	if d.decomp, err = c.NewReader(d.blocks); err != nil {
+		return nil, err
+	}
+	d.data = io.TeeReader(d.decomp, d.hash)
+	d.r = bin.NewReader(d.data)
+	return d, nil
+}
+
+// readRecord reads key and value of record of dump. Returns empty key at the end of dump.
+// Checksums of dump are verified at the end of dump.
+func (d *dumpReader) readRecord() (key, val []byte, err error) {
+	if d.eof {
+		return nil, nil, errDumpRecordsAfterEOF
+	}
+	if key, err = d.r.ReadBytes(); err != nil {
+		return nil, nil, dumpReadErr(err)
+	}
+	if len(key) == 0 { // EOF
+		d.eof = true
+		return nil, nil, d.verifyTrailer()
+	}
+	if val, err = d.r.ReadBytes(); err != nil {
+		return nil, nil, dumpReadErr(err)
+	}
+	d.info.NumRecords++
+	return
+}
+
+func (d *dumpReader) verifyTrailer() error {
+	if d.info.Version == dumpLegacyVersion {
+		return nil
+	}
+	// read the rest of data and blocks
+	if _, err := io.Copy(io.Discard, d.data); err != nil {
+		return dumpReadErr(err)
+	}
+	if _, err := io.Copy(io.Discard, d.blocks); err != nil {
+		return err
+	}
+	var trailer [8 + sha256.Size]byte
+	if _, err := io.ReadFull(d.src, trailer[:]); err != nil {
+		return dumpReadErr(err)
+	}
+	if binary.BigEndian.Uint64(trailer[:8]) != d.info.NumRecords || !bytes.Equal(trailer[8:], d.hash.Sum(nil)) {
+		return ErrDumpCorrupted
+	}
+	return nil
+}
+
+func (d *dumpReader) Close() error {
+	return d.decomp.Close()
+}
+
+func dumpReadErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrDumpCorrupted
+	}
+	return err
+}
+
+// ------------------------------------
+// blockWriter writes data by blocks with checksums
+type blockWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (b *blockWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		m := dumpBlockSize - len(b.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		b.buf, p, n = append(b.buf, p[:m]...), p[m:], n+m
+		if len(b.buf) == dumpBlockSize {
+			if err = b.flush(); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (b *blockWriter) flush() error {
+	var h [8]byte
+	binary.BigEndian.PutUint32(h[:4], uint32(len(b.buf)))
+	binary.BigEndian.PutUint32(h[4:], crc32.ChecksumIEEE(b.buf))
+	if _, err := b.w.Write(h[:]); err != nil {
+		return err
+	}
+	_, err := b.w.Write(b.buf)
+	b.buf = b.buf[:0]
+	return err
+}
+
+// Close writes the rest of data and the end block
+func (b *blockWriter) Close() error {
+	if len(b.buf) > 0 {
+		if err := b.flush(); err != nil {
+			return err
+		}
+	}
+	return b.flush() // end block
+}
+
+// blockReader reads data by blocks and verifies checksums of blocks
+type blockReader struct {
+	r   io.Reader
+	buf []byte
+	eof bool
+}
+
+func (b *blockReader) Read(p []byte) (n int, err error) {
+	for len(b.buf) == 0 {
+		if b.eof {
+			return 0, io.EOF
+		}
+		var h [8]byte
+		if _, err = io.ReadFull(b.r, h[:]); err != nil {
+			return 0, dumpReadErr(err)
+		}
+		size, crc := binary.BigEndian.Uint32(h[:4]), binary.BigEndian.Uint32(h[4:])
+		if size > dumpBlockSize {
+			return 0, ErrDumpCorrupted
+		}
+		if size == 0 {
+			b.eof = true
+			continue
+		}
+		b.buf = make([]byte, size)
+		if _, err = io.ReadFull(b.r, b.buf); err != nil {
+			return 0, dumpReadErr(err)
+		}
+		if crc32.ChecksumIEEE(b.buf) != crc {
+			return 0, ErrDumpCorrupted
+		}
+	}
+	n = copy(p, b.buf)
+	b.buf = b.buf[n:]
+	return
+}
@@ -0,0 +1,88 @@
+package goldb
+
+import (
+	"bytes"
+	"compress/flate"
+	"os"
+	"testing"
+
+	"github.com/denisskin/bin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyDump(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 100)
+	store.Dump(store.dir+".dump", &DumpOptions{Filter: NewQuery(TestTable)})
+
+	info, err := VerifyDump(store.dir + ".dump")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, info.Version)
+	assert.Equal(t, "flate", info.Codec)
+	assert.Equal(t, Key(TestTable), info.Filter)
+	assert.EqualValues(t, 101, info.NumRecords) // including meta-record
+	assert.False(t, info.Created.IsZero())
+}
+
+func TestVerifyDump_corrupted(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 100)
+	store.Dump(store.dir+".dump", nil)
+	data, _ := os.ReadFile(store.dir + ".dump")
+	corrupted := append([]byte{}, data...)
+	corrupted[len(data)/2] ^= 0xff
+	os.WriteFile(store.dir+".corrupted", corrupted, 0644)
+	os.WriteFile(store.dir+".truncated", data[:len(data)-10], 0644)
+
+	_, err1 := VerifyDump(store.dir + ".corrupted")
+	_, err2 := VerifyDump(store.dir + ".truncated")
+
+	assert.Equal(t, ErrDumpCorrupted, err1)
+	assert.Equal(t, ErrDumpCorrupted, err2)
+}
+
+func TestStorage_Restore_corrupted(t *testing.T) {
+	store1 := newTestStorage()
+	store2 := newTestStorage()
+	defer store1.Drop()
+	defer store2.Drop()
+	putTestValues(store1, 100)
+	putTestValues(store2, 10)
+	store1.Dump(store1.dir+".dump", nil)
+	data, _ := os.ReadFile(store1.dir + ".dump")
+	os.WriteFile(store1.dir+".dump", data[:len(data)-1], 0644)
+
+	err := store2.Restore(store1.dir + ".dump")
+
+	assert.Equal(t, ErrDumpCorrupted, err)
+	n, _ := store2.GetNumRows(NewQuery(TestTable))
+	assert.EqualValues(t, 10, n) // data is not changed
+}
+
+func TestStorage_Restore_legacy(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	buf := bytes.NewBuffer(nil)
+	fl, _ := flate.NewWriter(buf, flate.DefaultCompression)
+	w := bin.NewWriter(fl)
+	for i := 0; i < 10; i++ {
+		w.WriteVar(Key(TestTable, "Key", i))
+		w.WriteVar(encodeValue("Value"))
+	}
+	w.WriteVar([]byte{}) // EOF
+	fl.Close()
+	os.WriteFile(store.dir+".dump", buf.Bytes(), 0644)
+
+	info, err1 := VerifyDump(store.dir + ".dump")
+	err2 := store.Restore(store.dir + ".dump")
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, 1, info.Version)
+	assert.EqualValues(t, 10, info.NumRecords)
+	n, _ := store.GetNumRows(NewQuery(TestTable))
+	assert.EqualValues(t, 10, n)
+}
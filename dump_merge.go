@@ -0,0 +1,165 @@
+package goldb
+
+import (
+	"bytes"
+	gocontext "context"
+	"time"
+)
+
+// dumpMerge is state of merge restore of dump into live storage.
+//
+// System records of dump are handled explicitly:
+//   - sequences are merged as maximum of existing and dumped values;
+//   - ttl of dumped keys is set to merged keys after merging of all records (except keys kept by conflict policy);
+//   - rows of declared indexes and of expiry index are skipped (they are updated by merged records);
+//   - change log and dump meta-records are skipped.
+type dumpMerge struct {
+	op       *RestoreOptions
+	indexIDs map[Entity]bool
+	ttls     map[string]int64 // merged key -> expiry time (unix nano)
+	kept     map[string]bool  // existing keys kept by conflict policy
+}
+
+// mergeRestore imports records of dump into live storage in chunks of 10000 records
+func (s *Storage) mergeRestore(ctx gocontext.Context, r *dumpReader, key, val []byte, op *RestoreOptions) (err error) {
+	m := &dumpMerge{
+		op:       op,
+		indexIDs: indexEntities(),
+		ttls:     map[string]int64{},
+		kept:     map[string]bool{},
+	}
+	const chunkSize = 10000
+	var changes []Change
+	flush := func() error {
+		err := s.ExecCtx(ctx, func(tr *Transaction) {
+			for _, c := range changes {
+				m.apply(tr, c)
+			}
+		})
+		changes = changes[:0]
+		return err
+	}
+	for len(key) != 0 { // empty key - EOF
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		if c, ok := m.change(key, val); ok {
+			if changes = append(changes, c); len(changes) >= chunkSize {
+				if err = flush(); err != nil {
+					return
+				}
+			}
+		}
+		if key, val, err = r.readRecord(); err != nil {
+			return
+		}
+	}
+	if len(changes) > 0 {
+		if err = flush(); err != nil {
+			return
+		}
+	}
+
+	// set ttl of merged keys
+	var keys [][]byte
+	setTTLs := func() error {
+		err := s.ExecCtx(ctx, func(tr *Transaction) {
+			for _, key := range keys {
+				if data, _ := tr.getRaw(key); data != nil {
+					tr.setTTL(key, time.Unix(0, m.ttls[string(key)]))
+				}
+			}
+		})
+		keys = keys[:0]
+		return err
+	}
+	for key := range m.ttls {
+		if m.kept[key] {
+			continue
+		}
+		if keys = append(keys, []byte(key)); len(keys) >= chunkSize {
+			if err = setTTLs(); err != nil {
+				return
+			}
+		}
+	}
+	if len(keys) > 0 {
+		if err = setTTLs(); err != nil {
+			return
+		}
+	}
+	s.initTTL()
+	s.initChangeLog()
+	return
+}
+
+// change returns change of storage by record of dump; ok is false for records that are not merged as changes
+func (m *dumpMerge) change(key, val []byte) (c Change, ok bool) {
+	c = Change{OpPut, key, val}
+	if bytes.HasPrefix(key, dumpTombstoneKey) {
+		c = Change{OpDelete, key[len(dumpTombstoneKey):], nil}
+	}
+	tab, err := decodeUint(c.Key)
+	if err != nil {
+		return c, false
+	}
+	switch Entity(tab) {
+	case tabChangeLog, tabChangeLogMeta, tabDumpMeta, tabExpiryIndex, tabTTLEntities, tabKeyMigrations, tabKeyMigrationRows:
+		return c, false
+
+	case tabExpires:
+		var k []byte
+		var deadline int64
+		if c.Op == OpPut && (Record{Key: c.Key}).DecodeKey(&k) == nil && decodeValue(c.Value, &deadline) == nil {
+			if m.op.RewriteKey != nil {
+				k = m.op.RewriteKey(k)
+			}
+			if k != nil {
+				m.ttls[string(k)] = deadline
+			}
+		}
+		return c, false
+
+	case tabSequences: // sequences of rewritten entities are unknown
+		return c, c.Op == OpPut && m.op.RewriteKey == nil
+	}
+	if m.indexIDs[Entity(tab)] {
+		return c, false
+	}
+	if m.op.RewriteKey != nil {
+		if c.Key = m.op.RewriteKey(c.Key); c.Key == nil {
+			return c, false
+		}
+	}
+	return c, true
+}
+
+// apply applies change of merge restore by conflict policy
+func (m *dumpMerge) apply(t *Transaction, c Change) {
+	if tab, _ := decodeUint(c.Key); Entity(tab) == tabSequences {
+		var cur, seq uint64
+		t.GetVar(c.Key, &cur)
+		if decodeValue(c.Value, &seq) == nil && seq > cur {
+			t.PutVar(c.Key, seq)
+		}
+		return
+	}
+	if m.op.Conflict != ConflictOverwrite {
+		if existing, _ := t.Get(c.Key); existing != nil {
+			if m.op.Conflict == ConflictKeep {
+				m.kept[string(c.Key)] = true
+				return
+			}
+			if c.Value = m.op.Resolve(c.Key, existing, c.Value); c.Value == nil {
+				c.Op = OpDelete
+			} else {
+				c.Op = OpPut
+			}
+		}
+	}
+	if c.Op == OpDelete {
+		t.Delete(c.Key)
+	} else {
+		t.Put(c.Key, c.Value)
+	}
+}
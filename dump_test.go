@@ -1,8 +1,12 @@
 package goldb
 
 import (
+	"bytes"
+	gocontext "context"
 	"fmt"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/denisskin/gosys"
 	"github.com/stretchr/testify/assert"
@@ -46,6 +50,34 @@ func TestStorage_Restore(t *testing.T) {
 	})
 }
 
+func TestStorage_RestoreCtx_cancel(t *testing.T) {
+	store1 := newTestStorage()
+	store2 := newTestStorage()
+	defer store1.Drop()
+	defer store2.Drop()
+	store1.EnableChangeLog(0)
+	putTestValues(store1, 100)
+	op := &DumpOptions{}
+	store1.Dump(store1.dir+".dump", op)
+	store1.PutVar(Key(TestTable, "Key", 1), "new value")
+	store1.Dump(store1.dir+".inc", &DumpOptions{Incremental: true, Since: op.Seq})
+	store2.PutVar(Key(TestTable, "A"), "old value")
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+
+	err1 := store2.RestoreCtx(ctx, store1.dir+".dump")
+	v1, _ := store2.GetStr(Key(TestTable, "A"))
+	store2.Restore(store1.dir + ".dump")
+	err2 := store2.RestoreCtx(ctx, store1.dir+".inc")
+	v2, _ := store2.GetStr(Key(TestTable, "Key", 1))
+
+	assert.Equal(t, gocontext.Canceled, err1)
+	assert.Equal(t, "old value", v1) // data of storage is not changed
+	assert.False(t, fileExists(store2.dir+".restore"))
+	assert.Equal(t, gocontext.Canceled, err2)
+	assert.Equal(t, "Value 1", v2)
+}
+
 func putTestValues(store *Storage, n int) {
 	store.Exec(func(tr *Transaction) {
 		for i := 0; i < n; i++ {
@@ -56,3 +88,263 @@ func putTestValues(store *Storage, n int) {
 		}
 	})
 }
+
+func TestStorage_Dump_incremental(t *testing.T) {
+	store1 := newTestStorage()
+	store2 := newTestStorage()
+	defer store1.Drop()
+	defer store2.Drop()
+	store1.EnableChangeLog(0)
+	putTestValues(store1, 100)
+	op := &DumpOptions{}
+	store1.Dump(store1.dir+".dump", op)
+	store1.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, "Key", 1), "new value")
+		tr.PutVar(Key(TestTable, "Key", 1000), "Value 3e8")
+		tr.Delete(Key(TestTable, "Key", 2))
+	})
+	op1 := &DumpOptions{Incremental: true, Since: op.Seq}
+	err1 := store1.Dump(store1.dir+".inc1", op1)
+	store1.Exec(func(tr *Transaction) {
+		tr.Delete(Key(TestTable, "Key", 3))
+	})
+	err2 := store1.Dump(store1.dir+".inc2", &DumpOptions{Incremental: true, Since: op1.Seq})
+
+	err := store2.RestoreChain(store1.dir+".dump", store1.dir+".inc1", store1.dir+".inc2")
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 100, op.Seq)
+	assert.EqualValues(t, 103, op1.Seq)
+	n1, _ := store1.GetNumRows(NewQuery(TestTable))
+	n2, _ := store2.GetNumRows(NewQuery(TestTable))
+	assert.Equal(t, n1, n2)
+	store1.Fetch(NewQuery(TestTable), func(rec Record) error {
+		val, err := store2.Get(rec.Key)
+
+		assert.NoError(t, err)
+		assert.Equal(t, rec.Value, val)
+		return nil
+	})
+}
+
+func TestStorage_Restore_brokenChain(t *testing.T) {
+	store1 := newTestStorage()
+	store2 := newTestStorage()
+	defer store1.Drop()
+	defer store2.Drop()
+	store1.EnableChangeLog(0)
+	putTestValues(store1, 10)
+	store1.Dump(store1.dir+".inc", &DumpOptions{Incremental: true, Since: 5})
+
+	err := store2.Restore(store1.dir + ".inc")
+
+	assert.Equal(t, ErrDumpChain, err)
+}
+
+func TestStorage_Dump_incrementalDisabled(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	err := store.Dump(store.dir+".inc", &DumpOptions{Incremental: true})
+
+	assert.Equal(t, ErrChangeLogDisabled, err)
+	assert.False(t, fileExists(store.dir+".inc"))
+}
+
+func TestStorage_DumpCtx_failed(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 10)
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+
+	errCodec := store.Dump(store.dir+".dump1", &DumpOptions{Codec: "unknown"})
+	errLevel := store.Dump(store.dir+".dump2", &DumpOptions{CompressionLevel: 100})
+	errCtx := store.DumpCtx(ctx, store.dir+".dump3", nil)
+
+	assert.Equal(t, ErrDumpCodec, errCodec)
+	assert.Error(t, errLevel)
+	assert.Equal(t, gocontext.Canceled, errCtx)
+	assert.False(t, fileExists(store.dir+".dump1"))
+	assert.False(t, fileExists(store.dir+".dump2"))
+	assert.False(t, fileExists(store.dir+".dump3"))
+}
+
+func TestStorage_RestoreWith_merge(t *testing.T) {
+	for _, policy := range []ConflictPolicy{ConflictOverwrite, ConflictKeep, ConflictResolve} {
+		store1 := newTestStorage()
+		store2 := newTestStorage()
+		putTestValues(store1, 10)
+		store1.Dump(store1.dir+".dump", nil)
+		store2.Exec(func(tr *Transaction) {
+			tr.PutVar(Key(TestTable, "Key", 1), "old value")
+			tr.PutVar(Key(TestTable+1, 1), "other entity")
+		})
+
+		err := store2.RestoreWith(store1.dir+".dump", &RestoreOptions{
+			Merge:    true,
+			Conflict: policy,
+			Resolve: func(key, existing, dumped []byte) []byte {
+				return encodeValue("resolved")
+			},
+		})
+
+		assert.NoError(t, err)
+		n, _ := store2.GetNumRows(NewQuery(TestTable))
+		assert.EqualValues(t, 10, n)
+		v1, _ := store2.GetStr(Key(TestTable, "Key", 1))
+		v2, _ := store2.GetStr(Key(TestTable, "Key", 2))
+		v3, _ := store2.GetStr(Key(TestTable+1, 1))
+		assert.Equal(t, map[ConflictPolicy]string{
+			ConflictOverwrite: "Value 1",
+			ConflictKeep:      "old value",
+			ConflictResolve:   "resolved",
+		}[policy], v1)
+		assert.Equal(t, "Value 2", v2)
+		assert.Equal(t, "other entity", v3)
+		store1.Drop()
+		store2.Drop()
+	}
+}
+
+func TestStorage_RestoreWith_rewriteKey(t *testing.T) {
+	store1 := newTestStorage()
+	store2 := newTestStorage()
+	defer store1.Drop()
+	defer store2.Drop()
+	putTestValues(store1, 10)
+	store1.Dump(store1.dir+".dump", nil)
+
+	err := store2.RestoreWith(store1.dir+".dump", &RestoreOptions{
+		Merge: true,
+		RewriteKey: func(key []byte) []byte {
+			var s string
+			var i int
+			Record{Key: key}.DecodeKey(&s, &i)
+			if i%2 != 0 {
+				return nil
+			}
+			return Key(TestTable+1, i)
+		},
+	})
+
+	assert.NoError(t, err)
+	n1, _ := store2.GetNumRows(NewQuery(TestTable))
+	n2, _ := store2.GetNumRows(NewQuery(TestTable + 1))
+	assert.EqualValues(t, 0, n1)
+	assert.EqualValues(t, 5, n2)
+}
+
+func TestStorage_RestoreWith_mergeSystemRecords(t *testing.T) {
+	store1 := newTestStorage()
+	store2 := newTestStorage()
+	defer store1.Drop()
+	defer store2.Drop()
+	store1.Exec(func(tr *Transaction) {
+		for i := 0; i < 5; i++ {
+			tr.SequenceNextVal(TestTable)
+		}
+		tr.SequenceNextVal(TestTable + 1)
+	})
+	store1.PutWithTTL(Key(TestTable, "A"), []byte("Alice"), time.Second)
+	store1.Dump(store1.dir+".dump", nil)
+	store2.Exec(func(tr *Transaction) {
+		tr.SequenceNextVal(TestTable)
+		for i := 0; i < 3; i++ {
+			tr.SequenceNextVal(TestTable + 1)
+		}
+	})
+
+	err := store2.RestoreWith(store1.dir+".dump", &RestoreOptions{
+		Merge: true,
+		RewriteKey: func(key []byte) []byte {
+			return append(Key(TestTable+2), key...)
+		},
+	})
+	defer shiftTime(time.Minute)()
+
+	assert.NoError(t, err)
+	var seq1, seq2 uint64
+	store2.Exec(func(tr *Transaction) {
+		seq1, seq2 = tr.SequenceCurVal(TestTable), tr.SequenceCurVal(TestTable+1)
+	})
+	assert.EqualValues(t, 1, seq1) // sequences are not merged with rewritten keys
+	assert.EqualValues(t, 3, seq2)
+	n, _ := store2.GetNumRows(NewQuery(TestTable + 2))
+	assert.EqualValues(t, 0, n) // the key is expired
+	removed, errRemove := store2.RemoveExpired(100)
+	assert.NoError(t, errRemove)
+	assert.Equal(t, 1, removed)
+}
+
+func TestStorage_RestoreWith_mergeSequences(t *testing.T) {
+	store1 := newTestStorage()
+	store2 := newTestStorage()
+	defer store1.Drop()
+	defer store2.Drop()
+	store1.Exec(func(tr *Transaction) {
+		for i := 0; i < 5; i++ {
+			tr.SequenceNextVal(TestTable)
+		}
+		tr.SequenceNextVal(TestTable + 1)
+	})
+	store1.Dump(store1.dir+".dump", nil)
+	store2.Exec(func(tr *Transaction) {
+		tr.SequenceNextVal(TestTable)
+		for i := 0; i < 3; i++ {
+			tr.SequenceNextVal(TestTable + 1)
+		}
+	})
+
+	err := store2.RestoreWith(store1.dir+".dump", &RestoreOptions{Merge: true})
+
+	assert.NoError(t, err)
+	var seq1, seq2 uint64
+	store2.Exec(func(tr *Transaction) {
+		seq1, seq2 = tr.SequenceCurVal(TestTable), tr.SequenceCurVal(TestTable+1)
+	})
+	assert.EqualValues(t, 5, seq1)
+	assert.EqualValues(t, 3, seq2)
+}
+
+func TestStorage_DumpTo(t *testing.T) {
+	store1 := newTestStorage()
+	store2 := newTestStorage()
+	defer store1.Drop()
+	defer store2.Drop()
+	putTestValues(store1, 100)
+	buf := bytes.NewBuffer(nil)
+
+	err1 := store1.DumpTo(buf, nil)
+	err2 := store2.RestoreFrom(buf, nil)
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	n, _ := store2.GetNumRows(NewQuery(TestTable))
+	assert.EqualValues(t, 100, n)
+}
+
+func TestStorage_RestoreFrom_pipe(t *testing.T) {
+	store1 := newTestStorage()
+	store2 := newTestStorage()
+	defer store1.Drop()
+	defer store2.Drop()
+	putTestValues(store1, 100)
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(store1.DumpTo(w, nil))
+	}()
+
+	err := store2.RestoreFrom(r, nil)
+
+	assert.NoError(t, err)
+	store1.Fetch(NewQuery(TestTable), func(rec Record) error {
+		val, err := store2.Get(rec.Key)
+
+		assert.NoError(t, err)
+		assert.Equal(t, rec.Value, val)
+		return nil
+	})
+}
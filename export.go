@@ -0,0 +1,288 @@
+package goldb
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// ExportFormat is format of human-readable export of data
+type ExportFormat int
+
+const (
+	FormatJSONLines ExportFormat = iota // line: {"entity":"users","key":{"id":1},"value":{...}}
+	FormatCSV                           // header: names of key-values and "value"; supports one entity
+)
+
+// ExportOptions is options of export and import of data
+type ExportOptions struct {
+	Format  ExportFormat
+	Schemas []*KeySchema // schemas of entities; all registered schemas are used when empty
+}
+
+var errExportCSVSchemas = errors.New("goldb: export to CSV supports exactly one entity")
+
+type exportLine struct {
+	Entity string                     `json:"entity"`
+	Key    map[string]json.RawMessage `json:"key"`
+	Value  json.RawMessage            `json:"value"`
+}
+
+// Export writes records of entities with decoded key-values and values (by key schemas) in JSON Lines or CSV format.
+// Options may be nil (JSON Lines format of all entities with registered key schemas).
+func (s *Storage) Export(w io.Writer, options *ExportOptions) (err error) {
+	op := exportOptions(options)
+	if op.Format == FormatCSV && len(op.Schemas) != 1 {
+		return errExportCSVSchemas
+	}
+	snap, err := s.Snapshot()
+	if err != nil {
+		return
+	}
+	defer snap.Release()
+
+	bw := bufio.NewWriter(w)
+	cw := csv.NewWriter(bw)
+	for _, schema := range op.Schemas {
+		if op.Format == FormatCSV {
+			if err = cw.Write(schema.csvHeader()); err != nil {
+				return
+			}
+		}
+		err = snap.Fetch(NewQuery(schema.Entity), func(rec Record) error {
+			if op.Format == FormatCSV {
+				row, err := schema.csvRow(rec)
+				if err != nil {
+					return err
+				}
+				return cw.Write(row)
+			}
+			line, err := schema.jsonLine(rec)
+			if err != nil {
+				return err
+			}
+			_, err = bw.Write(append(line, '\n'))
+			return err
+		})
+		if err != nil {
+			return
+		}
+	}
+	if cw.Flush(); cw.Error() != nil {
+		return cw.Error()
+	}
+	return bw.Flush()
+}
+
+// Import reads records exported by Export and puts them to storage
+func (s *Storage) Import(r io.Reader, options *ExportOptions) (err error) {
+	op := exportOptions(options)
+	var recs []Record
+	flush := func() error {
+		err := s.Exec(func(tr *Transaction) {
+			for _, rec := range recs {
+				tr.Put(rec.Key, rec.Value)
+			}
+		})
+		recs = recs[:0]
+		return err
+	}
+	fnRecord := func(rec Record) error {
+		if recs = append(recs, rec); len(recs) >= 10000 {
+			return flush()
+		}
+		return nil
+	}
+	if op.Format == FormatCSV {
+		if len(op.Schemas) != 1 {
+			return errExportCSVSchemas
+		}
+		err = op.Schemas[0].readCSV(r, fnRecord)
+	} else {
+		err = readJSONLines(r, op.Schemas, fnRecord)
+	}
+	if err == nil && len(recs) > 0 {
+		err = flush()
+	}
+	return
+}
+
+func exportOptions(options *ExportOptions) (op ExportOptions) {
+	if options != nil {
+		op = *options
+	}
+	if len(op.Schemas) == 0 {
+		op.Schemas = registeredKeySchemas()
+	}
+	return
+}
+
+func (s *KeySchema) jsonLine(rec Record) ([]byte, error) {
+	vv, err := s.DecodeKey(rec)
+	if err != nil {
+		return nil, err
+	}
+	line := exportLine{Entity: s.Name, Key: map[string]json.RawMessage{}}
+	for i, v := range vv {
+		if line.Key[s.Parts[i].Name], err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	v, err := s.DecodeValue(rec)
+	if err != nil {
+		return nil, err
+	}
+	if line.Value, err = json.Marshal(v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(line)
+}
+
+func readJSONLines(r io.Reader, schemas []*KeySchema, fnRecord func(Record) error) error {
+	byName := map[string]*KeySchema{}
+	for _, s := range schemas {
+		byName[s.Name] = s
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 64*1024*1024)
+	for sc.Scan() {
+		var line exportLine
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			return err
+		}
+		schema := byName[line.Entity]
+		if schema == nil {
+			return fmt.Errorf("goldb: unknown entity %q", line.Entity)
+		}
+		vv := make([]interface{}, len(schema.Parts))
+		for i, p := range schema.Parts {
+			ptr := reflect.New(p.Type)
+			if err := json.Unmarshal(line.Key[p.Name], ptr.Interface()); err != nil {
+				return fmt.Errorf("goldb: invalid key-value %q of entity %q: %v", p.Name, line.Entity, err)
+			}
+			vv[i] = ptr.Elem().Interface()
+		}
+		val, err := schema.unmarshalValue(func(v interface{}) error {
+			return json.Unmarshal(line.Value, v)
+		})
+		if err != nil {
+			return err
+		}
+		if err = fnRecord(Record{Key(schema.Entity, vv...), val}); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+func (s *KeySchema) csvHeader() []string {
+	header := make([]string, 0, len(s.Parts)+1)
+	for _, p := range s.Parts {
+		header = append(header, p.Name)
+	}
+	return append(header, SchemaValueName)
+}
+
+func (s *KeySchema) csvRow(rec Record) ([]string, error) {
+	vv, err := s.DecodeKey(rec)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.DecodeValue(rec)
+	if err != nil {
+		return nil, err
+	}
+	row := make([]string, 0, len(vv)+1)
+	for _, v := range append(vv, v) {
+		cell, err := csvCell(v)
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, cell)
+	}
+	return row, nil
+}
+
+func (s *KeySchema) readCSV(r io.Reader, fnRecord func(Record) error) error {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(header, s.csvHeader()) {
+		return fmt.Errorf("goldb: invalid CSV header of entity %q", s.Name)
+	}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		vv := make([]interface{}, len(s.Parts))
+		for i, p := range s.Parts {
+			ptr := reflect.New(p.Type)
+			if err = parseCSVCell(row[i], ptr.Interface()); err != nil {
+				return fmt.Errorf("goldb: invalid key-value %q of entity %q: %v", p.Name, s.Name, err)
+			}
+			vv[i] = ptr.Elem().Interface()
+		}
+		val, err := s.unmarshalValue(func(v interface{}) error {
+			return parseCSVCell(row[len(s.Parts)], v)
+		})
+		if err != nil {
+			return err
+		}
+		if err = fnRecord(Record{Key(s.Entity, vv...), val}); err != nil {
+			return err
+		}
+	}
+}
+
+// unmarshalValue decodes value of record by function unmarshal and encodes it to storage format
+func (s *KeySchema) unmarshalValue(unmarshal func(v interface{}) error) ([]byte, error) {
+	if s.Value == nil { // raw bytes
+		var data []byte
+		err := unmarshal(&data)
+		return data, err
+	}
+	ptr := reflect.New(s.Value)
+	if err := unmarshal(ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return encodeValue(ptr.Elem().Interface()), nil
+}
+
+// csvCell returns strings as is and other values as JSON (unquoted JSON-strings)
+func csvCell(v interface{}) (string, error) {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.String {
+		return rv.String(), nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > 0 && data[0] == '"' {
+		return strconv.Unquote(string(data))
+	}
+	return string(data), nil
+}
+
+func parseCSVCell(cell string, ptr interface{}) error {
+	if p := reflect.ValueOf(ptr).Elem(); p.Kind() == reflect.String {
+		p.SetString(cell)
+		return nil
+	}
+	if err := json.Unmarshal([]byte(cell), ptr); err != nil {
+		return json.Unmarshal([]byte(strconv.Quote(cell)), ptr)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package goldb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testExportSchema = NewKeySchema(TestTable, "values").Part("name", "").Part("id", 0).ValueOf("")
+
+func TestStorage_Export(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 2)
+
+	buf := bytes.NewBuffer(nil)
+	err := store.Export(buf, &ExportOptions{Schemas: []*KeySchema{testExportSchema}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ""+
+		`{"entity":"values","key":{"id":0,"name":"Key"},"value":"Value 0"}`+"\n"+
+		`{"entity":"values","key":{"id":1,"name":"Key"},"value":"Value 1"}`+"\n",
+		buf.String(),
+	)
+}
+
+func TestStorage_Export_CSV(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 2)
+
+	buf := bytes.NewBuffer(nil)
+	err := store.Export(buf, &ExportOptions{Format: FormatCSV, Schemas: []*KeySchema{testExportSchema}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "name,id,value\nKey,0,Value 0\nKey,1,Value 1\n", buf.String())
+}
+
+func TestStorage_Import(t *testing.T) {
+	for _, format := range []ExportFormat{FormatJSONLines, FormatCSV} {
+		op := &ExportOptions{Format: format, Schemas: []*KeySchema{testExportSchema}}
+		store1, store2 := newTestStorage(), newTestStorage()
+		putTestValues(store1, 1000)
+		buf := bytes.NewBuffer(nil)
+
+		err1 := store1.Export(buf, op)
+		err2 := store2.Import(buf, op)
+
+		assert.NoError(t, err1)
+		assert.NoError(t, err2)
+		n, _ := store2.GetNumRows(NewQuery(TestTable))
+		assert.Equal(t, uint64(1000), n)
+		var v string
+		store2.GetVar(Key(TestTable, "Key", 0x3e7), &v)
+		assert.Equal(t, "Value 3e7", v)
+		store1.Drop()
+		store2.Drop()
+	}
+}
+
+func TestStorage_Import_invalidCSVHeader(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	err := store.Import(strings.NewReader("id,value\n1,a\n"), &ExportOptions{Format: FormatCSV, Schemas: []*KeySchema{testExportSchema}})
+
+	assert.Error(t, err)
+}
+
+func TestStorage_Export_registeredSchemas(t *testing.T) {
+	const tab Entity = 401
+	RegisterKeySchema(NewKeySchema(tab, "sessions").Part("id", uint64(0)).ValueOf(""))
+	store1, store2 := newTestStorage(), newTestStorage()
+	defer store1.Drop()
+	defer store2.Drop()
+	store1.PutVar(Key(tab, uint64(1)), "token")
+	buf := bytes.NewBuffer(nil)
+
+	err1 := store1.Export(buf, nil)
+	exported := buf.String()
+	err2 := store2.Import(buf, nil)
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Contains(t, exported, `{"entity":"sessions","key":{"id":1},"value":"token"}`)
+	v, _ := store2.GetStr(Key(tab, uint64(1)))
+	assert.Equal(t, "token", v)
+}
@@ -0,0 +1,164 @@
+package goldb
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Index is secondary index of entity.
+// Rows of index are updated by Transaction.Put and Transaction.Delete of primary rows in the same transaction.
+// Index row has key Key(ID, Values(rec)...) (followed by key-values of primary row for non-unique index)
+// and value equal to key of primary row.
+type Index struct {
+	ID     Entity                         // entity of index rows
+	Table  Entity                         // entity of primary rows
+	Unique bool                           // index key can refer to only one primary row
+	Values func(rec Record) []interface{} // returns key-values of index by primary record; nil - record is not indexed
+}
+
+var ErrUniqueIndex = errors.New("goldb: unique index violation")
+
+var indexes = struct {
+	sync.RWMutex
+	m map[Entity][]*Index
+}{m: map[Entity][]*Index{}}
+
+// AddIndex declares secondary index of entity.
+// Indexes are declared globally (like key encodings), so they are maintained by all storages of the process.
+// Use Storage.RebuildIndex to index existing data.
+func AddIndex(idx *Index) {
+	indexes.Lock()
+	defer indexes.Unlock()
+	indexes.m[idx.Table] = append(indexes.m[idx.Table], idx)
+}
+
+// indexEntities returns set of entities of declared indexes
+func indexEntities() map[Entity]bool {
+	indexes.RLock()
+	defer indexes.RUnlock()
+	ids := map[Entity]bool{}
+	for _, idxs := range indexes.m {
+		for _, idx := range idxs {
+			ids[idx.ID] = true
+		}
+	}
+	return ids
+}
+
+// NewIndexQuery returns query to rows of index entity.
+// Results of the query are primary records referred by index rows.
+func NewIndexQuery(idxID Entity, filterVal ...interface{}) *Query {
+	q := NewQuery(idxID, filterVal...)
+	q.index = true
+	return q
+}
+
+// RebuildIndex rebuilds all rows of index by primary rows.
+// Index is rebuilt by transactions of bounded size, so other writers are not blocked for long;
+// rows changed during rebuilding are indexed by their transactions.
+func (s *Storage) RebuildIndex(idx *Index) error {
+	if _, err := s.RemoveByQueryChunked(NewQuery(idx.ID), nil); err != nil {
+		return err
+	}
+	const batchSize = 10000
+	q := NewQuery(idx.Table).Limit(batchSize)
+	for {
+		var keys [][]byte
+		if err := s.Fetch(q, func(rec Record) error {
+			keys = append(keys, append([]byte{}, rec.Key...))
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := s.Exec(func(tr *Transaction) {
+			for _, key := range keys {
+				if data, _ := tr.Get(key); data != nil { // index by current value of row
+					if idxKey := idx.key(Record{key, data}); idxKey != nil {
+						tr.putIndex(idx, idxKey, key)
+					}
+				}
+			}
+		}); err != nil {
+			return err
+		}
+		if q.NumRows < batchSize {
+			return nil
+		}
+	}
+}
+
+func keyIndexes(key []byte) []*Index {
+	indexes.RLock()
+	defer indexes.RUnlock()
+	if len(indexes.m) == 0 {
+		return nil
+	}
+	tableID, err := decodeUint(key)
+	if err != nil {
+		return nil
+	}
+	return indexes.m[Entity(tableID)]
+}
+
+func (idx *Index) key(rec Record) []byte {
+	vv := idx.Values(rec)
+	if vv == nil {
+		return nil
+	}
+	key := Key(idx.ID, vv...)
+	if !idx.Unique {
+		key = append(key, rec.Key[len(Key(idx.Table)):]...)
+	}
+	return key
+}
+
+// updateIndexes updates index rows by new value of primary row
+func (t *Transaction) updateIndexes(idxs []*Index, key, data []byte, deleted bool) {
+	old, _ := t.getRaw(key) // expired primary row still has index rows
+	for _, idx := range idxs {
+		var oldKey, newKey []byte
+		if old != nil {
+			oldKey = idx.key(Record{key, old})
+		}
+		if !deleted {
+			newKey = idx.key(Record{key, data})
+		}
+		if bytes.Equal(oldKey, newKey) {
+			continue
+		}
+		if oldKey != nil && (!idx.Unique || t.refersTo(oldKey, key)) { // unique key can be taken by other row after expiry
+			t.delete(oldKey)
+		}
+		if newKey != nil {
+			t.putIndex(idx, newKey, key)
+		}
+	}
+}
+
+func (t *Transaction) putIndex(idx *Index, idxKey, key []byte) {
+	if idx.Unique {
+		if pk, _ := t.getRaw(idxKey); pk != nil && !bytes.Equal(pk, key) && !t.expired(pk) {
+			t.Fail(ErrUniqueIndex)
+		}
+	}
+	t.put(idxKey, key)
+}
+
+// refersTo returns true when index row refers to primary key
+func (t *Transaction) refersTo(idxKey, key []byte) bool {
+	pk, _ := t.getRaw(idxKey)
+	return bytes.Equal(pk, key)
+}
+
+// primaryRecord returns primary record referred by index record (value is nil when primary row does not exist)
+func (c *context) primaryRecord(src queryContext, idxRec Record) (rec Record, err error) {
+	rec.Key = idxRec.Value
+	rec.Value, err = src.Get(rec.Key, c.ReadOptions)
+	if err == leveldb.ErrNotFound {
+		err = nil
+	}
+	return
+}
@@ -0,0 +1,146 @@
+package goldb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testIndexedTable Entity = 300 + iota
+	testIdxUserName
+	testIdxUserAge
+)
+
+func init() {
+	AddIndex(&Index{
+		ID:     testIdxUserName,
+		Table:  testIndexedTable,
+		Unique: true,
+		Values: func(rec Record) []interface{} {
+			var u User
+			rec.MustDecode(&u)
+			return []interface{}{u.Name}
+		},
+	})
+	AddIndex(&Index{
+		ID:    testIdxUserAge,
+		Table: testIndexedTable,
+		Values: func(rec Record) []interface{} {
+			var u User
+			rec.MustDecode(&u)
+			return []interface{}{u.Age}
+		},
+	})
+}
+
+func fetchUserNames(store *Storage, q *Query) (names []string) {
+	store.Fetch(q, func(rec Record) error {
+		var u User
+		rec.MustDecode(&u)
+		names = append(names, u.Name)
+		return nil
+	})
+	return
+}
+
+func TestTransaction_Put_indexes(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	err := store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(testIndexedTable, 1), User{"Alice", 22})
+		tr.PutVar(Key(testIndexedTable, 2), User{"Bob", 33})
+		tr.PutVar(Key(testIndexedTable, 3), User{"Cat", 22})
+	})
+	err2 := store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(testIndexedTable, 1), User{"Alina", 22}) // update name
+		tr.Delete(Key(testIndexedTable, 3))
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, err2)
+	assert.Equal(t, []string{"Alina"}, fetchUserNames(store, NewIndexQuery(testIdxUserName, "Alina")))
+	assert.Equal(t, []string(nil), fetchUserNames(store, NewIndexQuery(testIdxUserName, "Alice")))
+	assert.Equal(t, []string{"Alina"}, fetchUserNames(store, NewIndexQuery(testIdxUserAge, 22)))
+	assert.Equal(t, []string{"Bob"}, fetchUserNames(store, NewIndexQuery(testIdxUserAge, 33)))
+}
+
+func TestTransaction_Put_uniqueIndexViolation(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	store.PutVar(Key(testIndexedTable, 1), User{"Alice", 22})
+	err := store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(testIndexedTable, 2), User{"Alice", 33})
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"Alice"}, fetchUserNames(store, NewIndexQuery(testIdxUserAge, 22)))
+	assert.Equal(t, []string(nil), fetchUserNames(store, NewIndexQuery(testIdxUserAge, 33)))
+}
+
+func TestTransaction_Put_uniqueIndexOfExpiredRow(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	store.PutWithTTL(Key(testIndexedTable, 1), encodeValue(User{"Alice", 22}), time.Second)
+	defer shiftTime(time.Minute)()
+	err := store.PutVar(Key(testIndexedTable, 2), User{"Alice", 33}) // unique name of expired row
+	n, errRemove := store.RemoveExpired(100)
+
+	assert.NoError(t, err)
+	assert.NoError(t, errRemove)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"Alice"}, fetchUserNames(store, NewIndexQuery(testIdxUserName, "Alice")))
+	assert.Equal(t, []string{"Alice"}, fetchUserNames(store, NewIndexQuery(testIdxUserAge, 33)))
+}
+
+func TestStorage_RebuildIndex(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.PutVar(Key(testIndexedTable, 1), User{"Alice", 22})
+	store.PutVar(Key(testIndexedTable, 2), User{"Bob", 22})
+	store.Put(Key(testIdxUserAge, 99, 1), Key(testIndexedTable, 1)) // stale index row
+
+	err := store.RebuildIndex(&Index{
+		ID:    testIdxUserAge,
+		Table: testIndexedTable,
+		Values: func(rec Record) []interface{} {
+			var u User
+			rec.MustDecode(&u)
+			return []interface{}{u.Age}
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Bob"}, fetchUserNames(store, NewIndexQuery(testIdxUserAge, 22)))
+	assert.Equal(t, []string(nil), fetchUserNames(store, NewIndexQuery(testIdxUserAge, 99)))
+}
+
+func TestStorage_RebuildIndex_chunks(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	const n = 10001
+	store.Exec(func(tr *Transaction) {
+		for i := 1; i <= n; i++ {
+			tr.PutVar(Key(testIndexedTable, i), User{fmt.Sprintf("User %d", i), i % 10})
+		}
+	})
+
+	err := store.RebuildIndex(&Index{
+		ID:    testIdxUserAge,
+		Table: testIndexedTable,
+		Values: func(rec Record) []interface{} {
+			var u User
+			rec.MustDecode(&u)
+			return []interface{}{u.Age}
+		},
+	})
+	numRows, _ := store.GetNumRows(NewQuery(testIdxUserAge))
+
+	assert.NoError(t, err)
+	assert.Equal(t, n, int(numRows))
+}
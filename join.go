@@ -0,0 +1,224 @@
+package goldb
+
+import (
+	"bytes"
+
+	"github.com/denisskin/bin"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Join is intersection or union of queries to index entities.
+// Rows of each query must be sorted by row-ID, i.e. keys of index rows are filter of the query followed by row-ID,
+// and values are uint64 row-IDs (as for FetchID).
+// Results of queries are merged by key-values after filter using seeks, without loading them to memory.
+//
+//	j := Intersect(NewQuery(IdxUserCity, "London"), NewQuery(IdxUserAge, 33)).Limit(100)
+//	ids, err := store.QueryJoinIDs(j)
+type Join struct {
+	queries []*Query
+	union   bool
+	offset  []byte
+	limit   int64
+
+	// results
+	NumRows uint64
+}
+
+// Intersect returns join of rows existing in all queries
+func Intersect(qq ...*Query) *Join {
+	return &Join{queries: qq, limit: -1}
+}
+
+// Union returns join of rows existing in any of queries
+func Union(qq ...*Query) *Join {
+	return &Join{queries: qq, union: true, limit: -1}
+}
+
+func (j *Join) Limit(limit int64) *Join {
+	j.limit = limit
+	return j
+}
+
+// Offset sets key-values (relative to filters of queries) after which rows are fetched
+func (j *Join) Offset(offset ...interface{}) *Join {
+	var enc KeyEncoding
+	if len(j.queries) > 0 {
+		enc = j.queries[0].enc
+	}
+	j.offset = encodeKeyValues(bin.NewBuffer(nil), enc, offset).Bytes()
+	return j
+}
+
+func (j *Join) CurrentOffset() []byte {
+	return j.offset
+}
+
+// FetchJoinID fetches row-IDs of join of queries in ascending order of keys
+func (c *context) FetchJoinID(j *Join, fnRow func(id uint64) error) (err error) {
+	j.NumRows = 0
+	if len(j.queries) == 0 {
+		return
+	}
+	limit := j.limit
+	if limit < 0 {
+		limit = 1e15
+	}
+
+	c.rmx.RLock()
+	defer c.rmx.RUnlock()
+
+	its := make([]*joinIterator, len(j.queries))
+	for i, q := range j.queries {
+		its[i] = c.newJoinIterator(q)
+	}
+	defer func() {
+		if r, _ := recover().(error); r != nil && r != Break {
+			err = r
+		}
+		for _, it := range its {
+			if err == nil {
+				err = it.iter.Error()
+			}
+			it.iter.Release()
+		}
+		if err != nil && c.fPanicOnErr {
+			panic(err)
+		}
+	}()
+
+	for _, it := range its {
+		it.seekAfter(j.offset)
+	}
+	for limit > 0 {
+		var it *joinIterator
+		if j.union {
+			it = nextUnion(its)
+		} else {
+			it = nextIntersection(its)
+		}
+		if it == nil {
+			break
+		}
+		j.offset = append(j.offset[:0], it.suffix()...)
+		limit--
+		var id uint64
+		if id, err = decodeUint(it.iter.Value()); err != nil {
+			return
+		}
+		if err = fnRow(id); err != nil {
+			if err == Break {
+				err = nil
+			}
+			return
+		}
+		j.NumRows++
+
+		// move to next rows
+		for _, t := range its {
+			if t.valid && bytes.Equal(t.suffix(), j.offset) {
+				t.next()
+			}
+		}
+	}
+	return
+}
+
+// QueryJoinIDs returns slice of row-IDs of join of queries
+func (c *context) QueryJoinIDs(j *Join) (ids []uint64, err error) {
+	err = c.FetchJoinID(j, func(id uint64) error {
+		ids = append(ids, id)
+		return nil
+	})
+	return
+}
+
+// nextIntersection moves iterators to the least key existing in all of them (leapfrog join)
+func nextIntersection(its []*joinIterator) *joinIterator {
+	for {
+		var max []byte
+		for _, it := range its {
+			if !it.valid {
+				return nil
+			}
+			if s := it.suffix(); max == nil || bytes.Compare(s, max) > 0 {
+				max = s
+			}
+		}
+		max = append([]byte{}, max...)
+		found := true
+		for _, it := range its {
+			if !bytes.Equal(it.suffix(), max) {
+				found = false
+				if !it.seek(max) {
+					return nil
+				}
+			}
+		}
+		if found {
+			return its[0]
+		}
+	}
+}
+
+// nextUnion returns iterator with the least key
+func nextUnion(its []*joinIterator) (min *joinIterator) {
+	for _, it := range its {
+		if it.valid && (min == nil || bytes.Compare(it.suffix(), min.suffix()) < 0) {
+			min = it
+		}
+	}
+	return
+}
+
+type joinIterator struct {
+	c     *context
+	q     *Query
+	iter  iterator.Iterator
+	valid bool
+}
+
+func (c *context) newJoinIterator(q *Query) *joinIterator {
+	rng := q.keysRange()
+	return &joinIterator{c: c, q: q, iter: c.qCtx.NewIterator(&rng, nil)}
+}
+
+func (it *joinIterator) suffix() []byte {
+	return it.iter.Key()[len(it.q.filter):]
+}
+
+func (it *joinIterator) seek(suffix []byte) bool {
+	it.valid = it.iter.Seek(concat(it.q.filter, suffix))
+	return it.skipFiltered()
+}
+
+// seekAfter moves iterator to first row with key-values after offset (to first row when offset is empty)
+func (it *joinIterator) seekAfter(offset []byte) bool {
+	if len(offset) == 0 {
+		it.valid = it.iter.First()
+		return it.skipFiltered()
+	}
+	start := util.BytesPrefix(concat(it.q.filter, offset)).Limit
+	if start == nil {
+		it.valid = false
+		return false
+	}
+	it.valid = it.iter.Seek(start)
+	return it.skipFiltered()
+}
+
+func (it *joinIterator) next() bool {
+	it.valid = it.iter.Next()
+	return it.skipFiltered()
+}
+
+func (it *joinIterator) skipFiltered() bool {
+	for it.valid {
+		rec := Record{it.iter.Key(), it.iter.Value()}
+		if !it.c.expired(rec.Key) && (it.q.fnFilter == nil || it.q.fnFilter(rec)) {
+			break
+		}
+		it.valid = it.iter.Next()
+	}
+	return it.valid
+}
@@ -0,0 +1,64 @@
+package goldb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func putJoinTestIndexes(store *Storage) {
+	store.Exec(func(tr *Transaction) {
+		for id := uint64(1); id <= 30; id++ {
+			if id%2 == 0 {
+				tr.PutID(Key(TestTable, "even", id), id)
+			}
+			if id%3 == 0 {
+				tr.PutID(Key(TestTable, "div3", id), id)
+			}
+			if id%5 == 0 {
+				tr.PutID(Key(TestTable, "div5", id), id)
+			}
+		}
+	})
+}
+
+func TestContext_FetchJoinID_intersect(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putJoinTestIndexes(store)
+
+	ids, err := store.QueryJoinIDs(Intersect(NewQuery(TestTable, "even"), NewQuery(TestTable, "div3")))
+	ids3, err3 := store.QueryJoinIDs(Intersect(NewQuery(TestTable, "even"), NewQuery(TestTable, "div3"), NewQuery(TestTable, "div5")))
+
+	assert.NoError(t, err)
+	assert.NoError(t, err3)
+	assert.Equal(t, []uint64{6, 12, 18, 24, 30}, ids)
+	assert.Equal(t, []uint64{30}, ids3)
+}
+
+func TestContext_FetchJoinID_union(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putJoinTestIndexes(store)
+
+	j := Union(NewQuery(TestTable, "div3"), NewQuery(TestTable, "div5")).Limit(4)
+	ids1, err1 := store.QueryJoinIDs(j)
+	ids2, err2 := store.QueryJoinIDs(j) // next page
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, []uint64{3, 5, 6, 9}, ids1)
+	assert.Equal(t, []uint64{10, 12, 15, 18}, ids2)
+	assert.Equal(t, 4, int(j.NumRows))
+}
+
+func TestContext_FetchJoinID_offset(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putJoinTestIndexes(store)
+
+	ids, err := store.QueryJoinIDs(Intersect(NewQuery(TestTable, "even"), NewQuery(TestTable, "div5")).Offset(uint64(10)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{20, 30}, ids)
+}
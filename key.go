@@ -1,13 +1,58 @@
 package goldb
 
 import (
+	"errors"
 	"reflect"
+	"strings"
+	"sync"
 
 	"github.com/denisskin/bin"
 )
 
 type Entity int
 
+// KeyEncoding is format of encoding key-values of entity
+type KeyEncoding int
+
+const (
+	// KeyEncodingVar encodes numeric key-values as var-ints (default).
+	// Strings and []byte are escaped (0x00 -> 0x00 0xFF) and terminated by 0x00 0x01, so they may contain zero bytes.
+	KeyEncodingVar KeyEncoding = iota
+
+	// KeyEncodingOrdered encodes key-values so that byte order of keys matches order of values.
+	// Strings and []byte are escaped as in KeyEncodingVar; numbers are encoded with fixed size.
+	KeyEncodingOrdered
+
+	// KeyEncodingLegacy is format of keys stored by previous versions:
+	// strings are terminated by zero byte (so they must not contain zero bytes), []byte are prefixed by length.
+	// Keys of legacy format are migrated to current encoding of entity by Storage.MigrateKeys.
+	KeyEncodingLegacy
+)
+
+// ErrKeyZeroByte is panic-error of encoding string key-value with zero byte in KeyEncodingLegacy
+var ErrKeyZeroByte = errors.New("goldb: string key-value of legacy key contains zero byte")
+
+var keyEncodings = struct {
+	sync.RWMutex
+	m map[Entity]KeyEncoding
+}{m: map[Entity]KeyEncoding{}}
+
+// SetKeyEncoding sets format of encoding keys of entity.
+// Entities use KeyEncodingVar by default. Data stored by previous versions is readable with KeyEncodingLegacy
+// or can be migrated by Storage.MigrateKeys. Encoding should be set once before reading or writing entity data.
+func SetKeyEncoding(entityID Entity, enc KeyEncoding) {
+	keyEncodings.Lock()
+	defer keyEncodings.Unlock()
+	keyEncodings.m[entityID] = enc
+}
+
+// KeyEncoding returns format of encoding keys of entity
+func (e Entity) KeyEncoding() KeyEncoding {
+	keyEncodings.RLock()
+	defer keyEncodings.RUnlock()
+	return keyEncodings.m[e]
+}
+
 type DBEncoder interface {
 	DBEncode() []byte
 }
@@ -17,21 +62,41 @@ type DBDecoder interface {
 }
 
 func Key(entityID Entity, vv ...interface{}) []byte {
+	return encodeKey(entityID, entityID.KeyEncoding(), vv)
+}
+
+func encodeKey(entityID Entity, enc KeyEncoding, vv []interface{}) []byte {
 	w := bin.NewBuffer(nil)
 	w.WriteVarInt(int(entityID))
-	encodeKeyValues(w, vv)
-	return w.Bytes()
+	return encodeKeyValues(w, enc, vv).Bytes()
 }
 
 func PrimaryKey(tableID Entity, id uint64) []byte {
 	return Key(tableID, id)
 }
 
-func encodeKeyValues(w *bin.Buffer, vv []interface{}) *bin.Buffer {
+func encodeKeyValues(w *bin.Buffer, enc KeyEncoding, vv []interface{}) *bin.Buffer {
 	for _, v := range vv {
-		if s, ok := v.(string); ok {
-			w.Write(append([]byte(s), 0x00))
-		} else {
+		if enc == KeyEncodingOrdered {
+			w.Write(encodeOrderedKeyValue(nil, v))
+			continue
+		}
+		switch val := v.(type) {
+		case string:
+			if enc != KeyEncodingLegacy {
+				w.Write(appendEscaped(nil, val))
+			} else if strings.IndexByte(val, 0) >= 0 {
+				panic(ErrKeyZeroByte)
+			} else {
+				w.Write(append([]byte(val), 0x00))
+			}
+		case []byte:
+			if enc != KeyEncodingLegacy {
+				w.Write(appendEscaped(nil, string(val)))
+			} else {
+				w.WriteVar(val)
+			}
+		default:
 			w.WriteVar(v)
 		}
 	}
@@ -0,0 +1,162 @@
+package goldb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// Order-preserving encoding of key-values (KeyEncodingOrdered):
+//	signed integers   - 8 bytes big-endian with flipped sign bit
+//	unsigned integers - 8 bytes big-endian
+//	floats            - 8 bytes big-endian of IEEE-754 bits (sign bit flipped for positive; all bits for negative)
+//	time.Time         - 8 bytes of unix-seconds (as signed integer) + 4 bytes of nanoseconds
+//	bool              - 1 byte
+//	string, []byte    - bytes with escaped zero-byte (0x00 -> 0x00 0xFF) terminated by 0x00 0x01
+
+const signBit = 1 << 63
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func encodeOrderedKeyValue(buf []byte, v interface{}) []byte {
+	switch val := v.(type) {
+	case string:
+		return appendEscaped(buf, val)
+	case []byte:
+		return appendEscaped(buf, string(val))
+	case time.Time:
+		buf = binary.BigEndian.AppendUint64(buf, uint64(val.Unix())^signBit)
+		return binary.BigEndian.AppendUint32(buf, uint32(val.Nanosecond()))
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return append(buf, 1)
+		}
+		return append(buf, 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.BigEndian.AppendUint64(buf, uint64(rv.Int())^signBit)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.BigEndian.AppendUint64(buf, rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return binary.BigEndian.AppendUint64(buf, encodeOrderedFloat(rv.Float()))
+	case reflect.String:
+		return appendEscaped(buf, rv.String())
+	}
+	panic(fmt.Errorf("goldb: unsupported type of ordered key value %T", v))
+}
+
+func decodeOrderedKeyValues(data []byte, vv []interface{}) error {
+	for _, v := range vv {
+		n, err := decodeOrderedKeyValue(data, v)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func decodeOrderedKeyValue(data []byte, v interface{}) (n int, err error) {
+	switch val := v.(type) {
+	case *string:
+		var s []byte
+		s, n, err = readEscaped(data)
+		*val = string(s)
+		return
+	case *[]byte:
+		*val, n, err = readEscaped(data)
+		return
+	case *time.Time:
+		if len(data) < 12 {
+			return 0, errInvalidKeyData
+		}
+		sec := int64(binary.BigEndian.Uint64(data) ^ signBit)
+		*val = time.Unix(sec, int64(binary.BigEndian.Uint32(data[8:])))
+		return 12, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return 0, fmt.Errorf("goldb: can not decode key value to %T", v)
+	}
+	switch p := rv.Elem(); p.Kind() {
+	case reflect.Bool:
+		if len(data) < 1 {
+			return 0, errInvalidKeyData
+		}
+		p.SetBool(data[0] != 0)
+		return 1, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if len(data) < 8 {
+			return 0, errInvalidKeyData
+		}
+		p.SetInt(int64(binary.BigEndian.Uint64(data) ^ signBit))
+		return 8, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if len(data) < 8 {
+			return 0, errInvalidKeyData
+		}
+		p.SetUint(binary.BigEndian.Uint64(data))
+		return 8, nil
+	case reflect.Float32, reflect.Float64:
+		if len(data) < 8 {
+			return 0, errInvalidKeyData
+		}
+		p.SetFloat(decodeOrderedFloat(binary.BigEndian.Uint64(data)))
+		return 8, nil
+	case reflect.String:
+		s, n, err := readEscaped(data)
+		p.SetString(string(s))
+		return n, err
+	}
+	return 0, fmt.Errorf("goldb: can not decode key value to %T", v)
+}
+
+func appendEscaped(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x00 {
+			buf = append(buf, 0x00, 0xff)
+		} else {
+			buf = append(buf, s[i])
+		}
+	}
+	return append(buf, 0x00, 0x01)
+}
+
+func readEscaped(data []byte) (s []byte, n int, err error) {
+	s = []byte{}
+	for {
+		i := bytes.IndexByte(data[n:], 0)
+		if i < 0 || n+i+1 >= len(data) {
+			return nil, 0, errInvalidKeyData
+		}
+		s = append(s, data[n:n+i]...)
+		n += i + 2
+		switch data[n-1] {
+		case 0x01: // terminator
+			return
+		case 0xff: // escaped zero-byte
+			s = append(s, 0x00)
+		default:
+			return nil, 0, errInvalidKeyData
+		}
+	}
+}
+
+func encodeOrderedFloat(f float64) uint64 {
+	if bits := math.Float64bits(f); bits&signBit != 0 {
+		return ^bits
+	} else {
+		return bits | signBit
+	}
+}
+
+func decodeOrderedFloat(v uint64) float64 {
+	if v&signBit != 0 {
+		return math.Float64frombits(v &^ signBit)
+	}
+	return math.Float64frombits(^v)
+}
@@ -0,0 +1,196 @@
+package goldb
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/denisskin/bin"
+)
+
+const (
+	tabKeyMigrations    Entity = 0x7ffffff9 // (entity) -> state of migration of keys of entity
+	tabKeyMigrationRows Entity = 0x7ffffff8 // (entity, new key) -> (expiry time, value); rows staged by migration
+)
+
+// phases of migration of keys
+const (
+	migrationStaged  = iota + 1 // rows with keys in new encoding are copied to staging rows
+	migrationCleared            // rows with keys in old encoding are deleted
+	migrationDone               // staging rows are moved to entity
+)
+
+// keyMigration is state of migration of keys of entity
+type keyMigration struct {
+	From, To KeyEncoding
+	Phase    int
+}
+
+var errKeyMigrationChanged = errors.New("goldb: key encoding of entity is changed during migration of keys")
+
+// MigrateKeys rewrites keys of entity to current key encoding of the entity.
+// Keys are read in encoding of previous migration of the entity (KeyEncodingLegacy for entity that was never migrated).
+// Parameters vv are pointers to variables of types of key-values (as in Record.DecodeKey).
+//
+// Migration is made by batches of transactions: rows are copied to staging system rows with new keys,
+// then old rows are deleted, then staging rows are moved to entity.
+// State of migration is persisted, so interrupted migration is continued by repeated call,
+// and repeated call for migrated entity does nothing. Entity must not be changed during migration.
+func (s *Storage) MigrateKeys(entityID Entity, vv ...interface{}) (err error) {
+	m, err := s.keyMigration(entityID)
+	if err != nil {
+		return
+	}
+	to := entityID.KeyEncoding()
+	if m.Phase == 0 || m.Phase == migrationDone {
+		if m.Phase == migrationDone && m.To == to {
+			return nil
+		}
+		m = keyMigration{From: m.To, To: to} // m.To is KeyEncodingLegacy for entity that was never migrated
+	} else if m.To != to {
+		return errKeyMigrationChanged
+	}
+	if m.From == m.To {
+		m.Phase = migrationDone
+		return s.Exec(func(tr *Transaction) {
+			tr.put(Key(tabKeyMigrations, int(entityID)), m.encode())
+		})
+	}
+	for m.Phase < migrationDone {
+		switch m.Phase {
+		case 0:
+			err = s.stageMigratedKeys(entityID, m, vv)
+		case migrationStaged:
+			_, err = s.RemoveByQueryChunked(NewQuery(entityID), nil)
+		case migrationCleared:
+			err = s.moveMigratedKeys(entityID)
+		}
+		if err != nil {
+			return
+		}
+		m.Phase++
+		if err = s.Exec(func(tr *Transaction) {
+			tr.put(Key(tabKeyMigrations, int(entityID)), m.encode())
+		}); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// keyMigration returns persisted state of migration of keys of entity
+func (s *Storage) keyMigration(entityID Entity) (m keyMigration, err error) {
+	m.To = KeyEncodingLegacy
+	data, err := s.Get(Key(tabKeyMigrations, int(entityID)))
+	if err != nil || data == nil {
+		return
+	}
+	if len(data) != 3 {
+		return m, errInvalidKeyData
+	}
+	return keyMigration{KeyEncoding(data[0]), KeyEncoding(data[1]), int(data[2])}, nil
+}
+
+func (m keyMigration) encode() []byte {
+	return []byte{byte(m.From), byte(m.To), byte(m.Phase)}
+}
+
+// stageMigratedKeys copies rows of entity to staging rows with keys in new encoding
+func (s *Storage) stageMigratedKeys(entityID Entity, m keyMigration, vv []interface{}) error {
+	const batchSize = 10000
+	q := NewQuery(entityID).Limit(batchSize)
+	for {
+		var keys, newKeys, values [][]byte
+		cur := s.Cursor(q)
+		for cur.Next() {
+			rec := cur.Record()
+			newKey, err := migratedKey(entityID, rec.Key, m, vv)
+			if err != nil {
+				cur.Close()
+				return err
+			}
+			keys = append(keys, append([]byte{}, rec.Key...))
+			newKeys = append(newKeys, newKey)
+			values = append(values, append([]byte{}, rec.Value...))
+		}
+		if err := cur.Close(); err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			err := s.Exec(func(tr *Transaction) {
+				for i, key := range keys {
+					var deadline int64
+					if data, _ := tr.getRaw(Key(tabExpires, key)); data != nil {
+						decodeValue(data, &deadline)
+					}
+					w := bin.NewBuffer(nil)
+					w.WriteVarInt(int(deadline))
+					w.Write(values[i])
+					tr.put(Key(tabKeyMigrationRows, int(entityID), newKeys[i]), w.Bytes())
+				}
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if q.NumRows < batchSize {
+			return nil
+		}
+	}
+}
+
+// moveMigratedKeys moves staging rows to entity
+func (s *Storage) moveMigratedKeys(entityID Entity) error {
+	const batchSize = 10000
+	for {
+		var recs []Record
+		err := s.Fetch(NewQuery(tabKeyMigrationRows, int(entityID)).Limit(batchSize), func(rec Record) error {
+			recs = append(recs, Record{append([]byte{}, rec.Key...), append([]byte{}, rec.Value...)})
+			return nil
+		})
+		if err != nil || len(recs) == 0 {
+			return err
+		}
+		err = s.Exec(func(tr *Transaction) {
+			for _, rec := range recs {
+				var id int
+				var key []byte
+				rec.MustDecodeKey(&id, &key)
+				r := bin.NewBuffer(rec.Value)
+				deadline, err := r.ReadVarInt64()
+				if err != nil {
+					tr.Fail(err)
+				}
+				tr.Put(key, rec.Value[r.CntRead:])
+				if deadline != 0 {
+					tr.setTTL(key, time.Unix(0, deadline))
+				}
+				tr.delete(rec.Key)
+			}
+		})
+		if err != nil || len(recs) < batchSize {
+			return err
+		}
+	}
+}
+
+// migratedKey returns key in encoding m.To by key in encoding m.From
+func migratedKey(entityID Entity, key []byte, m keyMigration, vv []interface{}) ([]byte, error) {
+	if err := decodeKey(key, m.From, vv); err != nil {
+		return nil, err
+	}
+	values := derefValues(vv)
+	if !bytes.Equal(encodeKey(entityID, m.From, values), key) { // key has other types of key-values
+		return nil, errInvalidKeyData
+	}
+	return encodeKey(entityID, m.To, values), nil
+}
+
+func derefValues(vv []interface{}) []interface{} {
+	values := make([]interface{}, len(vv))
+	for i, v := range vv {
+		values[i] = reflect.ValueOf(v).Elem().Interface()
+	}
+	return values
+}
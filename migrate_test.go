@@ -0,0 +1,74 @@
+package goldb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_MigrateKeys(t *testing.T) {
+	const tab Entity = 100
+	store := newTestStorage()
+	defer store.Drop()
+	defer SetKeyEncoding(tab, KeyEncodingVar)
+
+	SetKeyEncoding(tab, KeyEncodingLegacy)
+	store.Exec(func(tr *Transaction) {
+		for i := -2; i <= 2; i++ {
+			tr.PutVar(Key(tab, "Key", i), i)
+		}
+	})
+
+	SetKeyEncoding(tab, KeyEncodingOrdered)
+	err1 := store.MigrateKeys(tab, new(string), new(int))
+	err2 := store.MigrateKeys(tab, new(string), new(int)) // entity is already migrated
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, []int{-2, -1, 0, 1, 2}, fetchMigratedKeys(t, store, tab))
+}
+
+func TestStorage_MigrateKeys_resume(t *testing.T) {
+	const tab Entity = 101
+	store := newTestStorage()
+	defer store.Drop()
+	defer SetKeyEncoding(tab, KeyEncodingVar)
+
+	SetKeyEncoding(tab, KeyEncodingLegacy)
+	store.Exec(func(tr *Transaction) {
+		for i := -2; i <= 2; i++ {
+			tr.PutVar(Key(tab, "Key", i), i)
+		}
+	})
+	legacyKey := Key(tab, "Key", 1)
+	SetKeyEncoding(tab, KeyEncodingOrdered)
+
+	// interrupted migration: rows are staged, some of legacy rows are deleted
+	m := keyMigration{From: KeyEncodingLegacy, To: KeyEncodingOrdered, Phase: migrationStaged}
+	errStage := store.stageMigratedKeys(tab, m, []interface{}{new(string), new(int)})
+	store.Exec(func(tr *Transaction) {
+		tr.put(Key(tabKeyMigrations, int(tab)), m.encode())
+		tr.Delete(legacyKey)
+	})
+	err1 := store.MigrateKeys(tab, new(string), new(int))
+	err2 := store.MigrateKeys(tab, new(string), new(int)) // repeated migration
+	numStaged, _ := store.GetNumRows(NewQuery(tabKeyMigrationRows))
+
+	assert.NoError(t, errStage)
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, 0, int(numStaged))
+	assert.Equal(t, []int{-2, -1, 0, 1, 2}, fetchMigratedKeys(t, store, tab))
+}
+
+func fetchMigratedKeys(t *testing.T, store *Storage, tab Entity) (keys []int) {
+	store.Fetch(NewQuery(tab, "Key"), func(rec Record) error {
+		var s string
+		var i int
+		rec.MustDecodeKey(&s, &i)
+		assert.Equal(t, i, int(rec.ValueInt()))
+		keys = append(keys, i)
+		return nil
+	})
+	return
+}
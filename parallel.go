@@ -0,0 +1,160 @@
+package goldb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// ParallelFetch fetches records by query concurrently by several workers from consistent snapshot of storage.
+// Keys range of the query is split into sub-ranges of roughly equal size (estimated by leveldb.DB.SizeOf),
+// so function fn is called concurrently and in arbitrary order. Order, offset and limit of the query are ignored.
+// When fn returns Break, fetching of all sub-ranges is stopped.
+// When fn returns error for a record, fetching of sub-ranges following the record's sub-range is stopped,
+// while preceding sub-ranges are fetched until their end or failure.
+// So returned error is deterministically the error of the first (in key order) failed sub-range.
+func (s *Storage) ParallelFetch(q *Query, workers int, fn func(rec Record) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	snap, err := s.Snapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	qRange := q.keysRange()
+	ranges := s.splitRange(snap, qRange, workers)
+	errs := make([]error, len(ranges))
+	stoppedFrom := int64(len(ranges)) // index of first stopped sub-range
+	var broken int32                  // fetching of all sub-ranges is stopped by Break
+	stop := func(i int64) {
+		for {
+			if cur := atomic.LoadInt64(&stoppedFrom); i >= cur || atomic.CompareAndSwapInt64(&stoppedFrom, cur, i) {
+				return
+			}
+		}
+	}
+	var numRows uint64
+	var wg sync.WaitGroup
+	for i, rng := range ranges {
+		wg.Add(1)
+		go func(i int, sq Query) {
+			defer wg.Done()
+			errs[i] = snap.Fetch(&sq, func(rec Record) error {
+				if int64(i) > atomic.LoadInt64(&stoppedFrom) || atomic.LoadInt32(&broken) != 0 {
+					return Break
+				}
+				err := fn(rec)
+				if err == Break {
+					atomic.StoreInt32(&broken, 1)
+				} else if err != nil {
+					stop(int64(i))
+				}
+				return err
+			})
+			if errs[i] != nil {
+				stop(int64(i))
+			}
+			atomic.AddUint64(&numRows, sq.NumRows)
+		}(i, q.subQuery(qRange, rng))
+	}
+	wg.Wait()
+
+	q.NumRows = numRows
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subQuery returns copy of the query limited by keys range rng (rng is part of qRange - keys range of the query)
+func (q *Query) subQuery(qRange, rng util.Range) Query {
+	sq := *q
+	sq.offset, sq.desc, sq.limit = nil, false, -1
+	if !bytes.Equal(rng.Start, qRange.Start) {
+		sq.from, sq.fromIncl = rng.Start[len(q.filter):], Inclusive
+	}
+	if !bytes.Equal(rng.Limit, qRange.Limit) {
+		sq.to, sq.toIncl = rng.Limit[len(q.filter):], Exclusive
+	}
+	return sq
+}
+
+// splitRange splits keys range into n sub-ranges of roughly equal data size
+func (s *Storage) splitRange(snap *Snapshot, rng util.Range, n int) []util.Range {
+	if n == 1 {
+		return []util.Range{rng}
+	}
+	// interpolate split keys between first and last keys of range
+	iter := snap.snap.NewIterator(&rng, nil)
+	var first, last []byte
+	if iter.First() {
+		first = append([]byte{}, iter.Key()...)
+		iter.Last()
+		last = append([]byte{}, iter.Key()...)
+	}
+	iter.Release()
+	if first == nil || bytes.Equal(first, last) {
+		return []util.Range{rng}
+	}
+	bounds := append([][]byte{rng.Start}, interpolateKeys(first, last, n*8)...)
+	bounds = append(bounds, rng.Limit)
+
+	// estimate size of parts and merge them into n groups
+	parts := make([]util.Range, len(bounds)-1)
+	for i := range parts {
+		parts[i] = util.Range{Start: bounds[i], Limit: bounds[i+1]}
+	}
+	sizes, err := s.db.SizeOf(parts)
+	total := sizes.Sum()
+	var ranges []util.Range
+	var size, start = int64(0), 0
+	for i := range parts {
+		if err != nil || total == 0 { // there are no estimations; split by number of parts
+			size++
+			total = int64(len(parts))
+		} else {
+			size += sizes[i]
+		}
+		if i == len(parts)-1 || size*int64(n) >= total*int64(len(ranges)+1) {
+			ranges = append(ranges, util.Range{Start: bounds[start], Limit: bounds[i+1]})
+			start = i + 1
+		}
+	}
+	return ranges
+}
+
+// interpolateKeys returns up to n-1 sorted keys between a and b (a < b) splitting the keys space into equal parts
+func interpolateKeys(a, b []byte, n int) (keys [][]byte) {
+	p := 0 // length of common prefix
+	for p < len(a) && p < len(b) && a[p] == b[p] {
+		p++
+	}
+	x, y := uint64Prefix(a[p:]), uint64Prefix(b[p:])
+	if y <= x {
+		return
+	}
+	step := (y - x) / uint64(n)
+	for i, prev := 1, x; i < n && step > 0; i++ {
+		v := x + step*uint64(i)
+		if v <= prev {
+			continue
+		}
+		key := binary.BigEndian.AppendUint64(append([]byte{}, a[:p]...), v)
+		keys = append(keys, bytes.TrimRight(key, "\x00"))
+		prev = v
+	}
+	return
+}
+
+func uint64Prefix(b []byte) uint64 {
+	var buf [8]byte
+	copy(buf[:], b)
+	return binary.BigEndian.Uint64(buf[:])
+}
@@ -0,0 +1,100 @@
+package goldb
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_ParallelFetch(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 1000)
+
+	var mx sync.Mutex
+	var vals = map[string]bool{}
+	q := NewQuery(TestTable, "Key")
+	err := store.ParallelFetch(q, 4, func(rec Record) error {
+		mx.Lock()
+		defer mx.Unlock()
+		vals[rec.ValueStr()] = true
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, len(vals))
+	assert.Equal(t, 1000, int(q.NumRows))
+}
+
+func TestStorage_ParallelFetch_error(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 1000)
+	errTest := errors.New("test error")
+
+	err := store.ParallelFetch(NewQuery(TestTable), 4, func(rec Record) error {
+		return errTest
+	})
+	errBreak := store.ParallelFetch(NewQuery(TestTable), 4, func(rec Record) error {
+		return Break
+	})
+
+	assert.Equal(t, errTest, err)
+	assert.NoError(t, errBreak)
+}
+
+func TestStorage_ParallelFetch_break(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 1000)
+	var keys [][]byte
+	store.Fetch(NewQuery(TestTable), func(rec Record) error {
+		keys = append(keys, append([]byte{}, rec.Key...))
+		return nil
+	})
+
+	var n int64
+	err := store.ParallelFetch(NewQuery(TestTable), 4, func(rec Record) error {
+		atomic.AddInt64(&n, 1)
+		if bytes.Compare(rec.Key, keys[900]) >= 0 { // break from the last sub-range
+			return Break
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Less(t, int(atomic.LoadInt64(&n)), 500)
+}
+
+func TestInterpolateKeys(t *testing.T) {
+	a, b := []byte("\x01A\x00"), []byte("\x01A\x80")
+
+	keys := interpolateKeys(a, b, 4)
+
+	assert.Equal(t, [][]byte{[]byte("\x01A\x20"), []byte("\x01A\x40"), []byte("\x01A\x60")}, keys)
+}
+
+func TestStorage_ParallelFetch_deterministicError(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 1000)
+	var first Record
+	store.Fetch(NewQuery(TestTable).First(), func(rec Record) error {
+		first = rec
+		return nil
+	})
+
+	for i := 0; i < 10; i++ {
+		err := store.ParallelFetch(NewQuery(TestTable), 4, func(rec Record) error {
+			return errors.New(rec.ValueStr())
+		})
+
+		assert.Equal(t, first.ValueStr(), err.Error())
+	}
+}
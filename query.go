@@ -1,32 +1,46 @@
 package goldb
 
 import (
+	"bytes"
 	"fmt"
+	"time"
 
 	"github.com/denisskin/bin"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 type Query struct {
 	// query params
 	filter   []byte
+	enc      KeyEncoding
 	offset   []byte
+	from     []byte // lower bound of keys range (relative to filter)
+	to       []byte // upper bound of keys range (relative to filter)
+	fromIncl bool
+	toIncl   bool
 	desc     bool
+	index    bool // query to index rows; fetches primary rows
 	limit    int64
 	fnFilter func(Record) bool
 
 	// results
-	NumRows uint64
+	NumRows     uint64        // number of fetched records
+	RowsScanned uint64        // number of records read from storage (including filtered ones)
+	BytesRead   uint64        // volume of keys and values read from storage
+	Seeks       uint64        // number of seeks of iterator and reads of primary records
+	Elapsed     time.Duration // time of execution
 }
 
 func NewQuery(idxID Entity, filterVal ...interface{}) *Query {
 	return &Query{
 		filter: Key(idxID, filterVal...),
+		enc:    idxID.KeyEncoding(),
 		limit:  -1,
 	}
 }
 
 func (q *Query) AddFilter(filterVal ...interface{}) *Query {
-	q.filter = append(q.filter, Key(0, filterVal...)[1:]...)
+	q.filter = append(q.filter, encodeKeyValues(bin.NewBuffer(nil), q.enc, filterVal).Bytes()...)
 	return q
 }
 
@@ -34,6 +48,42 @@ func (q *Query) String() string {
 	return fmt.Sprintf("{filter:%x, offset:%x, limit:%d, desc:%v}", q.filter, q.offset, q.limit, q.desc)
 }
 
+// Explain returns description of execution plan of the query
+func (q *Query) Explain() string {
+	rng := q.iterRange()
+	bound := func(b []byte, inf string) string {
+		if b == nil {
+			return inf
+		}
+		return fmt.Sprintf("%x", b)
+	}
+	order := "ascending"
+	if q.desc {
+		order = "descending"
+	}
+	s := fmt.Sprintf("scan range [%s, %s) in %s order", bound(rng.Start, "-inf"), bound(rng.Limit, "+inf"), order)
+	s += fmt.Sprintf("\n  prefix: %x", q.filter)
+	if len(q.offset) > 0 {
+		s += fmt.Sprintf("\n  offset: %x (exclusive)", q.offset)
+	}
+	if q.from != nil {
+		s += fmt.Sprintf("\n  from: %x (inclusive: %v)", q.from, q.fromIncl)
+	}
+	if q.to != nil {
+		s += fmt.Sprintf("\n  to: %x (inclusive: %v)", q.to, q.toIncl)
+	}
+	if q.limit >= 0 {
+		s += fmt.Sprintf("\n  limit: %d", q.limit)
+	}
+	if q.fnFilter != nil {
+		s += "\n  filter function: each scanned record"
+	}
+	if q.index {
+		s += "\n  index lookup: read primary record for each index record"
+	}
+	return s
+}
+
 func (q *Query) First() *Query {
 	return q.Limit(1).OrderAsk()
 }
@@ -48,10 +98,47 @@ func (q *Query) Limit(limit int64) *Query {
 }
 
 func (q *Query) Offset(offset ...interface{}) *Query {
-	q.offset = encodeKeyValues(bin.NewBuffer(nil), offset).Bytes()
+	q.offset = encodeKeyValues(bin.NewBuffer(nil), q.enc, offset).Bytes()
+	return q
+}
+
+// Bounds of keys range
+const (
+	Inclusive = true  // records with keys prefixed by bound-values are included to range
+	Exclusive = false // records with keys prefixed by bound-values are excluded from range
+)
+
+// From sets lower bound of keys range of the query (values relative to filter)
+func (q *Query) From(inclusive bool, vv ...interface{}) *Query {
+	q.from = encodeKeyValues(bin.NewBuffer(nil), q.enc, vv).Bytes()
+	q.fromIncl = inclusive
+	return q
+}
+
+// Until sets upper bound of keys range of the query (values relative to filter)
+func (q *Query) Until(inclusive bool, vv ...interface{}) *Query {
+	q.to = encodeKeyValues(bin.NewBuffer(nil), q.enc, vv).Bytes()
+	q.toIncl = inclusive
 	return q
 }
 
+// After sets lower bound of keys range: the query returns records with key-values greater than vv
+// (records with keys prefixed by vv are excluded). After works for both orders.
+func (q *Query) After(vv ...interface{}) *Query {
+	return q.From(Exclusive, vv...)
+}
+
+// Before sets upper bound of keys range: the query returns records with key-values less than vv
+// (records with keys prefixed by vv are excluded). Before works for both orders.
+func (q *Query) Before(vv ...interface{}) *Query {
+	return q.Until(Exclusive, vv...)
+}
+
+// Between sets keys range of the query: from <= key-value < to
+func (q *Query) Between(from, to interface{}) *Query {
+	return q.From(Inclusive, from).Until(Exclusive, to)
+}
+
 func (q *Query) OrderAsk() *Query {
 	q.desc = false
 	return q
@@ -75,3 +162,47 @@ func (q *Query) FilterFn(fn func(Record) bool) *Query {
 func (q *Query) CurrentOffset() []byte {
 	return q.offset
 }
+
+// keysRange returns range of keys of the query (without offset)
+func (q *Query) keysRange() (r util.Range) {
+	r = *util.BytesPrefix(q.filter)
+	if q.from != nil {
+		if r.Start = concat(q.filter, q.from); !q.fromIncl {
+			if r.Start = util.BytesPrefix(r.Start).Limit; r.Start == nil { // there are no keys after bound
+				return util.Range{Start: q.filter, Limit: q.filter}
+			}
+		}
+	}
+	if q.to != nil {
+		if r.Limit = concat(q.filter, q.to); q.toIncl {
+			r.Limit = util.BytesPrefix(r.Limit).Limit
+		}
+	}
+	return
+}
+
+// iterRange returns range of keys of the query starting from offset in order of the query.
+// Records with keys prefixed by offset are excluded.
+func (q *Query) iterRange() (r util.Range) {
+	r = q.keysRange()
+	if len(q.offset) == 0 {
+		return
+	}
+	start := concat(q.filter, q.offset)
+	if !q.desc { // records after offset
+		if next := util.BytesPrefix(start).Limit; next == nil { // there are no keys after offset
+			return util.Range{Start: start, Limit: start}
+		} else if bytes.Compare(next, r.Start) > 0 {
+			r.Start = next
+		}
+	} else { // records before offset
+		if r.Limit == nil || bytes.Compare(start, r.Limit) < 0 {
+			r.Limit = start
+		}
+	}
+	return
+}
+
+func concat(a, b []byte) []byte {
+	return append(append(make([]byte, 0, len(a)+len(b)), a...), b...)
+}
@@ -0,0 +1,143 @@
+package goldb
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Token format:
+//	version byte | flags byte | filter | offset | from | to | limit | [hmac-sha256 (16 bytes)]
+// where byte-slices are prefixed by uvarint-length and limit is varint.
+
+const tokenVersion = 1
+
+const (
+	tokenDesc = 1 << iota
+	tokenIndex
+	tokenFromIncl
+	tokenToIncl
+	tokenHasFrom
+	tokenHasTo
+	tokenSigned
+)
+
+const tokenMacSize = 16
+
+var ErrInvalidToken = errors.New("goldb: invalid query token")
+
+// Token returns string token of the query (filter, bounds, current offset, order and limit)
+// that can be used to continue fetching by QueryFromToken.
+// When secret is not nil the token is signed by HMAC-SHA256.
+// Token does not contain filtering function of the query.
+func (q *Query) Token(secret []byte) string {
+	var flags byte
+	if q.desc {
+		flags |= tokenDesc
+	}
+	if q.index {
+		flags |= tokenIndex
+	}
+	if q.fromIncl {
+		flags |= tokenFromIncl
+	}
+	if q.toIncl {
+		flags |= tokenToIncl
+	}
+	if q.from != nil {
+		flags |= tokenHasFrom
+	}
+	if q.to != nil {
+		flags |= tokenHasTo
+	}
+	if secret != nil {
+		flags |= tokenSigned
+	}
+	buf := bytes.NewBuffer([]byte{tokenVersion, flags})
+	for _, b := range [][]byte{q.filter, q.offset, q.from, q.to} {
+		writeUvarint(buf, uint64(len(b)))
+		buf.Write(b)
+	}
+	var n [binary.MaxVarintLen64]byte
+	buf.Write(n[:binary.PutVarint(n[:], q.limit)])
+	if secret != nil {
+		buf.Write(tokenMAC(secret, buf.Bytes()))
+	}
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes())
+}
+
+// QueryFromToken returns query by token made by Query.Token.
+// When secret is not nil the token must be signed by the same secret;
+// signed token can not be read without secret.
+func QueryFromToken(token string, secret []byte) (q *Query, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(data) < 2 || data[0] != tokenVersion {
+		return nil, ErrInvalidToken
+	}
+	flags := data[1]
+	if (secret != nil) != (flags&tokenSigned != 0) {
+		return nil, ErrInvalidToken
+	}
+	if secret != nil {
+		n := len(data) - tokenMacSize
+		if n < 2 || !hmac.Equal(data[n:], tokenMAC(secret, data[:n])) {
+			return nil, ErrInvalidToken
+		}
+		data = data[:n]
+	}
+
+	r := bytes.NewReader(data[2:])
+	q = &Query{
+		desc:     flags&tokenDesc != 0,
+		index:    flags&tokenIndex != 0,
+		fromIncl: flags&tokenFromIncl != 0,
+		toIncl:   flags&tokenToIncl != 0,
+	}
+	for _, p := range []*[]byte{&q.filter, &q.offset, &q.from, &q.to} {
+		if *p, err = readTokenBytes(r); err != nil {
+			return nil, ErrInvalidToken
+		}
+	}
+	if flags&tokenHasFrom == 0 {
+		q.from = nil
+	}
+	if flags&tokenHasTo == 0 {
+		q.to = nil
+	}
+	if q.limit, err = binary.ReadVarint(r); err != nil || r.Len() != 0 {
+		return nil, ErrInvalidToken
+	}
+	if len(q.filter) > 0 {
+		tableID, err := decodeUint(q.filter)
+		if err != nil {
+			return nil, ErrInvalidToken
+		}
+		q.enc = Entity(tableID).KeyEncoding()
+	}
+	return q, nil
+}
+
+func tokenMAC(secret, data []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(data)
+	return h.Sum(nil)[:tokenMacSize]
+}
+
+func writeUvarint(w io.Writer, v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func readTokenBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return nil, ErrInvalidToken
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
@@ -0,0 +1,51 @@
+package goldb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryFromToken(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 10)
+	secret := []byte("secret")
+
+	q := NewQuery(TestTable, "Key").OrderDesc().Limit(3)
+	store.Fetch(q, nil)
+	token := q.Token(secret)
+
+	q2, err := QueryFromToken(token, secret)
+	var vals []string
+	store.Fetch(q2, func(rec Record) error {
+		vals = append(vals, rec.ValueStr())
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Value 6", "Value 5", "Value 4"}, vals)
+	assert.Equal(t, q.String(), NewQuery(TestTable, "Key").Offset(7).OrderDesc().Limit(3).String())
+}
+
+func TestQueryFromToken_invalid(t *testing.T) {
+	secret := []byte("secret")
+	token := NewQuery(TestTable, "Key").Between(1, 2).Token(secret)
+
+	_, err1 := QueryFromToken(token, []byte("other secret"))
+	tampered := []byte(token)
+	tampered[len(tampered)/2] ^= 1
+	_, err2 := QueryFromToken(string(tampered), secret)
+	_, err3 := QueryFromToken(NewQuery(TestTable).Token(nil), secret)
+	_, err4 := QueryFromToken("", nil)
+	_, err5 := QueryFromToken(token, nil) // signed token without secret
+	q, err6 := QueryFromToken(NewQuery(TestTable, "Key").Between(1, 2).Token(nil), nil)
+
+	assert.Equal(t, ErrInvalidToken, err1)
+	assert.Equal(t, ErrInvalidToken, err2)
+	assert.Equal(t, ErrInvalidToken, err3)
+	assert.Equal(t, ErrInvalidToken, err4)
+	assert.Equal(t, ErrInvalidToken, err5)
+	assert.NoError(t, err6)
+	assert.Equal(t, NewQuery(TestTable, "Key").Between(1, 2).keysRange(), q.keysRange())
+}
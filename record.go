@@ -41,24 +41,49 @@ func (r Record) MustDecodeKey(vv ...interface{}) {
 }
 
 func (r Record) DecodeKey(vv ...interface{}) (err error) {
-	buf := bin.NewBuffer(r.Key)
+	tableID, err := decodeUint(r.Key)
+	if err != nil {
+		return
+	}
+	return decodeKey(r.Key, Entity(tableID).KeyEncoding(), vv)
+}
+
+func decodeKey(key []byte, enc KeyEncoding, vv []interface{}) (err error) {
+	buf := bin.NewBuffer(key)
 	if _, err = buf.ReadVarInt64(); err != nil { // read tableID
 		return
 	}
+	if enc == KeyEncodingOrdered {
+		return decodeOrderedKeyValues(key[int(buf.CntRead):], vv)
+	}
 	for _, v := range vv {
-		if str, ok := v.(*string); ok { // special case - read string in Key
-			if n := bytes.IndexByte(r.Key[int(buf.CntRead):], 0); n < 0 {
+		switch val := v.(type) {
+		case *string: // special case - read string in Key
+			if enc != KeyEncodingLegacy {
+				var s []byte
+				s, err = readKeyEscaped(buf, key)
+				*val = string(s)
+			} else if n := bytes.IndexByte(key[int(buf.CntRead):], 0); n < 0 {
 				return errInvalidKeyData
 			} else {
 				s := make([]byte, n+1)
 				if _, err = buf.Read(s); err != nil {
 					return
 				}
-				*str = string(s[:n])
+				*val = string(s[:n])
+			}
+		case *[]byte:
+			if enc != KeyEncodingLegacy {
+				*val, err = readKeyEscaped(buf, key)
+			} else {
+				buf.ReadVar(v)
 			}
-		} else {
+		default:
 			buf.ReadVar(v)
 		}
+		if err != nil {
+			return
+		}
 		if err = buf.Error(); err != nil {
 			return
 		}
@@ -66,6 +91,16 @@ func (r Record) DecodeKey(vv ...interface{}) (err error) {
 	return
 }
 
+// readKeyEscaped reads escaped string key-value from buffer of key
+func readKeyEscaped(buf *bin.Buffer, key []byte) ([]byte, error) {
+	s, n, err := readEscaped(key[int(buf.CntRead):])
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Read(make([]byte, n))
+	return s, err
+}
+
 func (r Record) KeyOffset(q *Query) []byte {
 	return r.Key[len(q.filter):]
 }
@@ -1,7 +1,9 @@
 package goldb
 
 import (
+	"bytes"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -25,6 +27,63 @@ func TestRecord_DecodeKey(t *testing.T) {
 	assert.Equal(t, []byte("Alice"), bb)
 }
 
+func TestRecord_DecodeKey_ordered(t *testing.T) {
+	tm := time.Date(1969, 7, 20, 20, 17, 40, 123, time.UTC)
+	rec := NewRecord(Key(TestOrderedTable, "Alice", -0x4567, uint8(200), -1.5, tm, true, []byte("Bob")), &User{"Alice", 22})
+
+	var (
+		s   string
+		num int
+		u   uint8
+		f   float64
+		ts  time.Time
+		ok  bool
+		bb  []byte
+	)
+	err := rec.DecodeKey(&s, &num, &u, &f, &ts, &ok, &bb)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice", s)
+	assert.Equal(t, -0x4567, num)
+	assert.Equal(t, uint8(200), u)
+	assert.Equal(t, -1.5, f)
+	assert.True(t, tm.Equal(ts))
+	assert.True(t, ok)
+	assert.Equal(t, []byte("Bob"), bb)
+}
+
+func TestRecord_DecodeKey_orderedZeroBytes(t *testing.T) {
+	rec := NewRecord(Key(TestOrderedTable, "A\x00B\x00", []byte{0, 0xff, 0}, "C"), &User{"Alice", 22})
+
+	var s1, s2 string
+	var bb []byte
+	err := rec.DecodeKey(&s1, &bb, &s2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "A\x00B\x00", s1)
+	assert.Equal(t, []byte{0, 0xff, 0}, bb)
+	assert.Equal(t, "C", s2)
+}
+
+func TestKey_ordered(t *testing.T) {
+	values := []interface{}{
+		-1 << 40, -256, -1, 0, 1, 255, 256, 1 << 40,
+	}
+	strs := []interface{}{
+		"", "\x00", "\x00\x00", "\x00\x01", "A", "A\x00", "A\x00B", "AB",
+	}
+	values = append(values, strs...)
+	for i := 1; i < len(values); i++ {
+		a := Key(TestOrderedTable, values[i-1])
+		b := Key(TestOrderedTable, values[i])
+		if i == len(values)-len(strs) { // compare only values of the same type
+			continue
+		}
+
+		assert.True(t, bytes.Compare(a, b) < 0, "%v < %v", values[i-1], values[i])
+	}
+}
+
 func TestRecord_Decode(t *testing.T) {
 	rec := NewRecord(Key(123, 0x456), &User{"Alice", 22})
 
@@ -36,3 +95,28 @@ func TestRecord_Decode(t *testing.T) {
 	assert.EqualValues(t, 0x456, rowID)
 	assert.Equal(t, User{"Alice", 22}, user)
 }
+
+func TestRecord_DecodeKey_zeroBytes(t *testing.T) {
+	rec := NewRecord(Key(TestTable, "A\x00B\x00", []byte{0, 0xff, 0}, "C", 5), &User{"Alice", 22})
+
+	var s1, s2 string
+	var bb []byte
+	var i int
+	err := rec.DecodeKey(&s1, &bb, &s2, &i)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "A\x00B\x00", s1)
+	assert.Equal(t, []byte{0, 0xff, 0}, bb)
+	assert.Equal(t, "C", s2)
+	assert.Equal(t, 5, i)
+}
+
+func TestKey_zeroByteInLegacyString(t *testing.T) {
+	const tab Entity = 102
+	SetKeyEncoding(tab, KeyEncodingLegacy)
+	defer SetKeyEncoding(tab, KeyEncodingVar)
+
+	assert.Panics(t, func() { Key(tab, "A\x00B") })
+	assert.NotPanics(t, func() { Key(TestTable, "A\x00B") })
+	assert.NotPanics(t, func() { Key(TestOrderedTable, "A\x00B") })
+}
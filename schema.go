@@ -0,0 +1,171 @@
+package goldb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// KeyPart is named typed key-value of record key
+type KeyPart struct {
+	Name string
+	Type reflect.Type
+}
+
+// KeySchema describes named typed key-values and type of value of records of entity.
+//
+//	RegisterKeySchema(NewKeySchema(TabUsers, "users").Part("id", uint64(0)).ValueOf(User{}))
+type KeySchema struct {
+	Entity Entity
+	Name   string
+	Parts  []KeyPart
+	Value  reflect.Type // type of value (nil - raw bytes)
+}
+
+// SchemaValueName is name of value of record in decoded map
+const SchemaValueName = "value"
+
+var keySchemas = struct {
+	sync.RWMutex
+	m map[Entity]*KeySchema
+}{m: map[Entity]*KeySchema{}}
+
+// NewKeySchema returns schema of entity without key-values
+func NewKeySchema(entityID Entity, name string) *KeySchema {
+	return &KeySchema{Entity: entityID, Name: name}
+}
+
+// Part adds key-value with name and type of v to schema
+func (s *KeySchema) Part(name string, v interface{}) *KeySchema {
+	s.Parts = append(s.Parts, KeyPart{name, reflect.TypeOf(v)})
+	return s
+}
+
+// ValueOf sets type of value of records to type of v
+func (s *KeySchema) ValueOf(v interface{}) *KeySchema {
+	s.Value = reflect.TypeOf(v)
+	return s
+}
+
+// RegisterKeySchema registers schema of entity
+func RegisterKeySchema(s *KeySchema) {
+	keySchemas.Lock()
+	defer keySchemas.Unlock()
+	keySchemas.m[s.Entity] = s
+}
+
+// KeySchemaOf returns registered schema of entity (nil - schema is not registered)
+func KeySchemaOf(entityID Entity) *KeySchema {
+	keySchemas.RLock()
+	defer keySchemas.RUnlock()
+	return keySchemas.m[entityID]
+}
+
+// registeredKeySchemas returns all registered schemas ordered by entity
+func registeredKeySchemas() []*KeySchema {
+	keySchemas.RLock()
+	defer keySchemas.RUnlock()
+	schemas := make([]*KeySchema, 0, len(keySchemas.m))
+	for _, s := range keySchemas.m {
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Entity < schemas[j].Entity })
+	return schemas
+}
+
+// DecodeKey returns key-values of record
+func (s *KeySchema) DecodeKey(rec Record) ([]interface{}, error) {
+	ptrs := make([]interface{}, len(s.Parts))
+	for i, p := range s.Parts {
+		ptrs[i] = reflect.New(p.Type).Interface()
+	}
+	if err := rec.DecodeKey(ptrs...); err != nil {
+		return nil, err
+	}
+	return derefValues(ptrs), nil
+}
+
+// DecodeValue returns value of record
+func (s *KeySchema) DecodeValue(rec Record) (interface{}, error) {
+	if s.Value == nil {
+		return rec.Value, nil
+	}
+	ptr := reflect.New(s.Value)
+	if err := rec.Decode(ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// DecodeMap decodes record to map of named key-values and value (by name SchemaValueName).
+// When schema is nil registered schema of record entity is used.
+func (r Record) DecodeMap(schema *KeySchema) (m map[string]interface{}, err error) {
+	if schema, err = r.schema(schema); err != nil {
+		return
+	}
+	vv, err := schema.DecodeKey(r)
+	if err != nil {
+		return
+	}
+	m = make(map[string]interface{}, len(vv)+1)
+	for i, v := range vv {
+		m[schema.Parts[i].Name] = v
+	}
+	if m[SchemaValueName], err = schema.DecodeValue(r); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Format returns human-readable presentation of record like `users(id=1, name="Alice"): {Alice 22}`.
+// When schema is nil registered schema of record entity is used.
+func (r Record) Format(schema *KeySchema) string {
+	schema, err := r.schema(schema)
+	if err != nil {
+		return r.String()
+	}
+	vv, err := schema.DecodeKey(r)
+	if err != nil {
+		return r.String()
+	}
+	parts := make([]string, len(vv))
+	for i, v := range vv {
+		parts[i] = schema.Parts[i].Name + "=" + formatKeyValue(v)
+	}
+	s := schema.Name + "(" + strings.Join(parts, ", ") + ")"
+	if v, err := schema.DecodeValue(r); err != nil {
+		s += fmt.Sprintf(": %x", r.Value)
+	} else if b, ok := v.([]byte); ok {
+		s += fmt.Sprintf(": %x", b)
+	} else {
+		s += fmt.Sprintf(": %v", v)
+	}
+	return s
+}
+
+func (r Record) schema(schema *KeySchema) (*KeySchema, error) {
+	if schema != nil {
+		return schema, nil
+	}
+	tableID, err := decodeUint(r.Key)
+	if err != nil {
+		return nil, err
+	}
+	if schema = KeySchemaOf(Entity(tableID)); schema == nil {
+		return nil, fmt.Errorf("goldb: key schema of entity %d is not registered", tableID)
+	}
+	return schema, nil
+}
+
+func formatKeyValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", val)
+	case []byte:
+		return fmt.Sprintf("%x", val)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
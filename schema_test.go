@@ -0,0 +1,34 @@
+package goldb
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord_DecodeMap(t *testing.T) {
+	schema := NewKeySchema(TestTable, "users").Part("group", "").Part("id", 0).ValueOf(User{})
+	rec := NewRecord(Key(TestTable, "admin", 0x123), User{"Alice", 22})
+
+	m, err := rec.DecodeMap(schema)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"group": "admin",
+		"id":    0x123,
+		"value": User{"Alice", 22},
+	}, m)
+}
+
+func TestRecord_Format(t *testing.T) {
+	const tab Entity = 400
+	RegisterKeySchema(NewKeySchema(tab, "users").Part("id", uint64(0)).Part("name", ""))
+	rec := NewRecord(Key(tab, uint64(1), "Alice"), "data")
+
+	s := rec.Format(nil)
+	s2 := NewRecord(Key(tab+1, 1), "data").Format(nil)
+
+	assert.Equal(t, `users(id=1, name="Alice"): `+fmt.Sprintf("%x", encodeValue("data")), s)
+	assert.Equal(t, NewRecord(Key(tab+1, 1), "data").String(), s2)
+}
@@ -0,0 +1,49 @@
+package goldb
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Snapshot is read-only context of consistent point-in-time view of storage.
+// Snapshot blocks neither readers nor writers. Open snapshots pin database of storage,
+// so Vacuum, Truncate and Restore of storage fail with ErrSnapshotsOpen until all snapshots are released.
+type Snapshot struct {
+	context
+	store *Storage
+	snap  *leveldb.Snapshot
+}
+
+var ErrSnapshotsOpen = errors.New("goldb: database is pinned by open snapshots")
+
+// Snapshot returns read-only context of current state of storage.
+// The snapshot must be released after use.
+func (s *Storage) Snapshot() (*Snapshot, error) {
+	s.rmx.RLock()
+	defer s.rmx.RUnlock()
+
+	snap, err := s.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt64(&s.cntSnapshots, 1)
+	ss := &Snapshot{store: s, snap: snap}
+	ss.qCtx = snap
+	ss.fTTL = &s.ttlTabs
+	ss.ReadOptions = s.ReadOptions
+	return ss, nil
+}
+
+// Release releases snapshot. Method waits for all reads of the snapshot
+func (ss *Snapshot) Release() {
+	ss.rmx.Lock()
+	defer ss.rmx.Unlock()
+
+	if ss.snap != nil {
+		ss.snap.Release()
+		ss.snap = nil
+		atomic.AddInt64(&ss.store.cntSnapshots, -1)
+	}
+}
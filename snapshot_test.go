@@ -0,0 +1,57 @@
+package goldb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_Snapshot(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.PutVar(Key(TestTable, "A"), "Alice")
+
+	snap, err := store.Snapshot()
+	store.PutVar(Key(TestTable, "A"), "Alina")
+	store.PutVar(Key(TestTable, "B"), "Bob")
+
+	v1, err1 := snap.GetStr(Key(TestTable, "A"))
+	n1, err2 := snap.GetNumRows(NewQuery(TestTable))
+	v2, _ := store.GetStr(Key(TestTable, "A"))
+	n2, _ := store.GetNumRows(NewQuery(TestTable))
+	snap.Release()
+	snap.Release()
+
+	assert.NoError(t, err)
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, "Alice", v1)
+	assert.Equal(t, 1, int(n1))
+	assert.Equal(t, "Alina", v2)
+	assert.Equal(t, 2, int(n2))
+}
+
+func TestStorage_Snapshot_truncate(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.PutVar(Key(TestTable, "A"), "Alice")
+	snap, _ := store.Snapshot()
+
+	errExec := store.Exec(func(tr *Transaction) { // writers are not blocked by snapshot
+		tr.PutVar(Key(TestTable, "B"), "Bob")
+	})
+	errTruncate1 := store.Truncate()
+	errVacuum := store.Vacuum()
+	v, errGet := snap.GetStr(Key(TestTable, "A"))
+	snap.Release()
+	errTruncate2 := store.Truncate()
+	n, _ := store.GetNumRows(NewQuery(TestTable))
+
+	assert.NoError(t, errExec)
+	assert.Equal(t, ErrSnapshotsOpen, errTruncate1)
+	assert.Equal(t, ErrSnapshotsOpen, errVacuum)
+	assert.NoError(t, errGet)
+	assert.Equal(t, "Alice", v)
+	assert.NoError(t, errTruncate2)
+	assert.Equal(t, 0, int(n))
+}
@@ -1,6 +1,8 @@
 package goldb
 
 import (
+	"bytes"
+	gocontext "context"
 	"fmt"
 	"log"
 	"os"
@@ -22,8 +24,23 @@ type Storage struct {
 	op  *opt.Options
 	mx  sync.Mutex
 
+	cntSnapshots int64 // number of open snapshots; database pinned by snapshots is not closed
+
 	cntWaitingTrans int64
 
+	// watchers of changes
+	watchMx     sync.Mutex
+	watchers    map[*Watcher]struct{}
+	cntWatchers int32
+
+	// change log
+	changeLog int32
+
+	// ttl params
+	ttlTabs    ttlEntities
+	reaperMx   sync.Mutex
+	reaperStop chan struct{}
+
 	// batch params
 	batchMx   sync.Mutex
 	batchExst chan struct{}
@@ -63,6 +80,8 @@ func (s *Storage) Open() error {
 	}
 	s.db = db
 	s.qCtx = db
+	s.initTTL()
+	s.initChangeLog()
 	return nil
 }
 
@@ -74,7 +93,14 @@ func (s *Storage) Recover() error {
 	}
 }
 
+// Close stops expiry reaper, closes all watchers (with error leveldb.ErrClosed) and closes database
 func (s *Storage) Close() error {
+	s.StopExpiryReaper()
+	s.closeWatchers(leveldb.ErrClosed)
+	return s.closeDB()
+}
+
+func (s *Storage) closeDB() error {
 	if s.db != nil {
 		if err := s.db.Close(); err != leveldb.ErrClosed {
 			return err
@@ -90,6 +116,14 @@ func (s *Storage) Drop() error {
 	return os.RemoveAll(s.dir)
 }
 
+// dropDB removes database files (the storage must be reopened)
+func (s *Storage) dropDB() error {
+	if err := s.closeDB(); err != nil {
+		return err
+	}
+	return os.RemoveAll(s.dir)
+}
+
 func (s *Storage) Size() (size int64) {
 	s.rmx.RLock()
 	defer s.rmx.RUnlock()
@@ -103,14 +137,28 @@ func (s *Storage) Size() (size int64) {
 	return
 }
 
+// lockDB waits for all readers and locks database for closing (s.rmx must be unlocked by caller).
+// Returns ErrSnapshotsOpen when database is pinned by open snapshots
+func (s *Storage) lockDB() error {
+	s.rmx.Lock()
+	if atomic.LoadInt64(&s.cntSnapshots) > 0 {
+		s.rmx.Unlock()
+		return ErrSnapshotsOpen
+	}
+	return nil
+}
+
+// Truncate removes all data of storage. Returns ErrSnapshotsOpen when storage has open snapshots
 func (s *Storage) Truncate() error {
 	s.mx.Lock()
 	defer s.mx.Unlock()
 
-	s.rmx.Lock() // wait for all readers
+	if err := s.lockDB(); err != nil {
+		return err
+	}
 	defer s.rmx.Unlock()
 
-	if err := s.Drop(); err != nil {
+	if err := s.dropDB(); err != nil {
 		return err
 	}
 	return s.Open()
@@ -123,53 +171,95 @@ func (s *Storage) WaitingTransactions() int64 {
 // Exec executes transaction.
 // The executing transaction can be discard by methods tx.Fail(err) or by panic(err)
 func (s *Storage) Exec(fn func(tx *Transaction)) (err error) {
+	return s.ExecCtx(gocontext.Background(), fn)
+}
+
+// ExecCtx executes transaction with context.
+// The transaction is discarded and ctx.Err() is returned when ctx is done before commit.
+// Fetching data inside the transaction is stopped when ctx is done.
+func (s *Storage) ExecCtx(ctx gocontext.Context, fn func(tx *Transaction)) (err error) {
 	atomic.AddInt64(&s.cntWaitingTrans, 1)
 	defer atomic.AddInt64(&s.cntWaitingTrans, -1)
 
 	s.mx.Lock()
 	defer s.mx.Unlock()
 
+	if err = ctx.Err(); err != nil {
+		return
+	}
 	t := &Transaction{}
 	t.tr, err = s.db.OpenTransaction()
 	if err != nil {
 		return
 	}
 	t.qCtx = t.tr
+	t.ctx = ctx
+	t.fTTL = &s.ttlTabs
 	t.fPanicOnErr = true
+	t.fTrackChanges = s.hasWatchers() || s.ChangeLogEnabled()
 	t.ReadOptions = s.ReadOptions
 	t.WriteOptions = s.WriteOptions
 
 	defer func() {
 		if r := recover(); r != nil {
 			t.Discard()
-			err = fmt.Errorf("goldb.Storage.Exec-error: %v", r)
+			if e := ctx.Err(); e != nil && r == e { // transaction is aborted by ctx
+				err = e
+			} else {
+				err = fmt.Errorf("goldb.Storage.Exec-error: %v", r)
+			}
 		}
 	}()
 
 	fn(t)
 
-	err = t.Commit()
+	if s.ChangeLogEnabled() {
+		t.writeChangeLog()
+	}
+	if err = ctx.Err(); err != nil {
+		t.Discard()
+		return
+	}
+	if err = t.Commit(); err == nil {
+		s.notifyWatchers(t.changes)
+	}
 	return
 }
 
 func (s *Storage) Vacuum() (err error) {
+	return s.VacuumCtx(gocontext.Background())
+}
+
+// VacuumCtx rewrites data of storage to new database. Vacuum is interrupted when ctx is done.
+// Returns ErrSnapshotsOpen when storage has open snapshots (e.g. of running Dump or ParallelFetch)
+func (s *Storage) VacuumCtx(ctx gocontext.Context) (err error) {
 	s.mx.Lock()
 	defer s.mx.Unlock()
 
-	tmpDir := s.dir + ".tmp"
-	oldDir := s.dir + ".old"
+	if atomic.LoadInt64(&s.cntSnapshots) > 0 { // fail before copying of data
+		return ErrSnapshotsOpen
+	}
 
+	tmpDir := s.dir + ".tmp"
 	defer os.RemoveAll(tmpDir)
 	os.RemoveAll(tmpDir)
-	os.RemoveAll(oldDir)
 
 	// copy db-data to new tmpDB
-	if err = s.copyDataToNewDB(tmpDir); err != nil {
+	if err = s.copyDataToNewDB(ctx, tmpDir); err != nil {
 		return
 	}
+	return s.replaceDB(tmpDir)
+}
+
+// replaceDB replaces database of storage by database in dir (the caller holds s.mx)
+func (s *Storage) replaceDB(dir string) (err error) {
+	oldDir := s.dir + ".old"
+	os.RemoveAll(oldDir)
 
 	// waiting for all readers
-	s.rmx.Lock()
+	if err = s.lockDB(); err != nil {
+		return
+	}
 	defer s.rmx.Unlock()
 
 	// close old db
@@ -181,7 +271,7 @@ func (s *Storage) Vacuum() (err error) {
 	if err = os.Rename(s.dir, oldDir); err != nil {
 		return
 	}
-	if err = os.Rename(tmpDir, s.dir); err != nil {
+	if err = os.Rename(dir, s.dir); err != nil {
 		return
 	}
 
@@ -195,7 +285,7 @@ func (s *Storage) Vacuum() (err error) {
 	return
 }
 
-func (s *Storage) copyDataToNewDB(dir string) (err error) {
+func (s *Storage) copyDataToNewDB(ctx gocontext.Context, dir string) (err error) {
 	db, err := leveldb.OpenFile(dir, s.op)
 	if err != nil {
 		return
@@ -218,6 +308,9 @@ func (s *Storage) copyDataToNewDB(dir string) (err error) {
 		if err = iterator.Error(); err != nil {
 			return
 		}
+		if err = ctx.Err(); err != nil {
+			return
+		}
 		if i%10000 == 0 {
 			if tr != nil {
 				if err = tr.Commit(); err != nil {
@@ -278,6 +371,31 @@ func (s *Storage) Delete(key []byte) error {
 	})
 }
 
+// PutIfAbsent puts data by key when the key does not exist; returns true when data was put
+func (s *Storage) PutIfAbsent(key, data []byte) (ok bool, err error) {
+	err = s.ExecBatch(func(tr *Transaction) {
+		ok = tr.PutIfAbsent(key, data)
+	})
+	return ok && err == nil, err
+}
+
+// CompareAndSwap puts newData by key when current value equals to oldData
+// (oldData is nil - the key must not exist); returns true when data was put
+func (s *Storage) CompareAndSwap(key, oldData, newData []byte) (ok bool, err error) {
+	err = s.ExecBatch(func(tr *Transaction) {
+		ok = tr.CompareAndSwap(key, oldData, newData)
+	})
+	return ok && err == nil, err
+}
+
+// DeleteIfEquals deletes key when its value equals to data; returns true when the key was deleted
+func (s *Storage) DeleteIfEquals(key, data []byte) (ok bool, err error) {
+	err = s.ExecBatch(func(tr *Transaction) {
+		ok = tr.DeleteIfEquals(key, data)
+	})
+	return ok && err == nil, err
+}
+
 func (s *Storage) RemoveByQuery(q *Query) error {
 	return s.ExecBatch(func(tr *Transaction) {
 		tr.Fetch(q, func(rec Record) error {
@@ -286,3 +404,91 @@ func (s *Storage) RemoveByQuery(q *Query) error {
 		})
 	})
 }
+
+// RemoveOptions is options of removing records by query in chunks
+type RemoveOptions struct {
+	ChunkSize int                                          // max number of records removed by one transaction (10000 by default)
+	Compact   bool                                         // compact keys range of removed records after removing (also when it is stopped)
+	Progress  func(numRemoved uint64, offset []byte) error // is called after each chunk; returned error (or Break) stops removing
+}
+
+// RemoveByQueryChunked removes records by query by several transactions of bounded size, so other writers are not blocked for long.
+// Offset of the query is moved after each committed chunk, so interrupted removing can be resumed by the same query
+// (or by the query restored from Query.Token). Limit of the query limits total number of removed records.
+func (s *Storage) RemoveByQueryChunked(q *Query, options *RemoveOptions) (numRemoved uint64, err error) {
+	op := RemoveOptions{ChunkSize: 10000}
+	if options != nil {
+		op = *options
+		if op.ChunkSize <= 0 {
+			op.ChunkSize = 10000
+		}
+	}
+	limit := q.limit
+	var removed util.Range // keys range of removed records
+	defer func() {
+		q.limit = limit
+		q.NumRows = numRemoved
+		if op.Compact && removed.Start != nil { // compact removed records also when removing is stopped
+			if errCompact := s.compactRange(removed); err == nil {
+				err = errCompact
+			}
+		}
+	}()
+
+	for {
+		chunk := int64(op.ChunkSize)
+		if limit >= 0 && limit-int64(numRemoved) < chunk {
+			if chunk = limit - int64(numRemoved); chunk <= 0 {
+				break
+			}
+		}
+		offset := q.offset
+		q.Limit(chunk)
+		var first, last []byte
+		if err = s.ExecBatch(func(tr *Transaction) {
+			q.offset, first, last = offset, nil, nil
+			tr.Fetch(q, func(rec Record) error {
+				if first == nil {
+					first = append([]byte{}, rec.Key...)
+				}
+				last = append(last[:0], rec.Key...)
+				tr.Delete(rec.Key)
+				return nil
+			})
+		}); err != nil {
+			q.offset = offset // chunk is not removed
+			return
+		}
+		if first != nil {
+			if bytes.Compare(first, last) > 0 { // descending order
+				first, last = last, first
+			}
+			if removed.Start == nil || bytes.Compare(first, removed.Start) < 0 {
+				removed.Start = first
+			}
+			if removed.Limit == nil || bytes.Compare(last, removed.Limit) >= 0 {
+				removed.Limit = append(last, 0)
+			}
+		}
+		numRemoved += q.NumRows
+		if op.Progress != nil {
+			if err = op.Progress(numRemoved, q.offset); err != nil {
+				if err == Break {
+					err = nil
+				}
+				return
+			}
+		}
+		if int64(q.NumRows) < chunk {
+			break
+		}
+	}
+	return
+}
+
+// compactRange compacts keys range of database
+func (s *Storage) compactRange(rng util.Range) error {
+	s.rmx.RLock()
+	defer s.rmx.RUnlock()
+	return s.db.CompactRange(rng)
+}
@@ -1,8 +1,11 @@
 package goldb
 
 import (
+	gocontext "context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -13,8 +16,13 @@ import (
 //------------------------------------
 const (
 	TestTable = iota + 1
+	TestOrderedTable
 )
 
+func init() {
+	SetKeyEncoding(TestOrderedTable, KeyEncodingOrdered)
+}
+
 func newTestStorage() *Storage {
 	return NewStorage(fmt.Sprintf("%s/test-goldb-%016x.db", os.TempDir(), time.Now().UnixNano()), nil)
 }
@@ -60,6 +68,293 @@ func TestContext_Fetch(t *testing.T) {
 	assert.Equal(t, 1, int(qA2.NumRows))
 }
 
+func TestContext_Fetch_orderedKeys(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	values := []float64{-1e10, -300, -2.5, -1, 0, 0.5, 1, 255, 256, 1e10}
+	store.Exec(func(tr *Transaction) {
+		for i := len(values) - 1; i >= 0; i-- {
+			tr.PutVar(Key(TestOrderedTable, "A", values[i]), i)
+		}
+	})
+
+	// query rows in descending order starting from -1
+	var res []float64
+	q := NewQuery(TestOrderedTable, "A").Offset(-1.0).OrderDesc()
+	err := store.Fetch(q, func(rec Record) error {
+		var s string
+		var f float64
+		rec.MustDecodeKey(&s, &f)
+		res = append(res, f)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{-2.5, -300, -1e10}, res)
+}
+
+func TestContext_Fetch_keysWithZeroBytes(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	for _, tab := range []Entity{TestTable, TestOrderedTable} {
+		store.Exec(func(tr *Transaction) {
+			tr.PutVar(Key(tab, "A", 1), "Alice")
+			tr.PutVar(Key(tab, "A\x00B", 2), "Bob")
+			tr.PutVar(Key(tab, "A\x00", 3), "Cat")
+		})
+
+		q := NewQuery(tab, "A")
+		err := store.Fetch(q, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, int(q.NumRows))
+	}
+}
+
+func TestContext_Fetch_between(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	store.Exec(func(tr *Transaction) {
+		for i := 0; i < 10; i++ {
+			tr.PutVar(Key(TestOrderedTable, "day", i, "row"), i)
+		}
+	})
+	fetch := func(q *Query) (res []int) {
+		err := store.Fetch(q, func(rec Record) error {
+			res = append(res, int(rec.ValueInt()))
+			return nil
+		})
+		assert.NoError(t, err)
+		return
+	}
+
+	assert.Equal(t, []int{2, 3, 4}, fetch(NewQuery(TestOrderedTable, "day").Between(2, 5)))
+	assert.Equal(t, []int{4, 3, 2}, fetch(NewQuery(TestOrderedTable, "day").Between(2, 5).OrderDesc()))
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5}, fetch(NewQuery(TestOrderedTable, "day").Until(Inclusive, 5)))
+	assert.Equal(t, []int{9, 8, 7}, fetch(NewQuery(TestOrderedTable, "day").From(Exclusive, 6).OrderDesc()))
+	assert.Equal(t, []int{4, 3}, fetch(NewQuery(TestOrderedTable, "day").Between(2, 5).Offset(5).OrderDesc().Limit(2)))
+}
+
+func TestContext_FetchCtx(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 100)
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	q := NewQuery(TestTable)
+	err := store.FetchCtx(ctx, q, func(rec Record) error {
+		if q.NumRows == 9 {
+			cancel()
+		}
+		return nil
+	})
+
+	assert.Equal(t, gocontext.Canceled, err)
+	assert.Equal(t, 10, int(q.NumRows))
+}
+
+func TestStorage_ExecCtx(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 100)
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	err := store.ExecCtx(ctx, func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, "A"), "Alice")
+		cancel()
+		tr.Fetch(NewQuery(TestTable), func(rec Record) error {
+			tr.Delete(rec.Key)
+			return nil
+		})
+	})
+	v, _ := store.GetStr(Key(TestTable, "A"))
+	n, _ := store.GetNumRows(NewQuery(TestTable))
+	errBatch := store.ExecBatchCtx(ctx, func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, "B"), "Bob")
+	})
+
+	assert.Equal(t, gocontext.Canceled, err)
+	assert.Equal(t, gocontext.Canceled, errBatch)
+	assert.Equal(t, "", v)
+	assert.Equal(t, 100, int(n))
+}
+
+func TestStorage_ExecCtx_panic(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	err := store.ExecCtx(ctx, func(tr *Transaction) {
+		cancel()
+		panic("test panic")
+	})
+
+	assert.Error(t, err)
+	assert.NotEqual(t, gocontext.Canceled, err) // panic is not reported as ctx error
+	assert.Contains(t, err.Error(), "test panic")
+}
+
+func TestStorage_VacuumCtx(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 100)
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	err := store.VacuumCtx(ctx)
+	n, _ := store.GetNumRows(NewQuery(TestTable))
+
+	assert.Equal(t, gocontext.Canceled, err)
+	assert.Equal(t, 100, int(n))
+	assert.False(t, fileExists(store.dir+".tmp"))
+}
+
+func TestContext_Fetch_descLongKeys(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	long := strings.Repeat("x", 2000)
+	store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, "A", long+"1"), 1)
+		tr.PutVar(Key(TestTable, "A", long+"2"), 2)
+		tr.PutVar(Key(TestTable, "A", long+"3"), 3)
+		tr.PutVar(Key(TestTable, "B"), 4)
+	})
+	fetch := func(q *Query) (res []int) {
+		store.Fetch(q, func(rec Record) error {
+			res = append(res, int(rec.ValueInt()))
+			return nil
+		})
+		return
+	}
+
+	assert.Equal(t, []int{3, 2, 1}, fetch(NewQuery(TestTable, "A").OrderDesc()))
+	assert.Equal(t, []int{1}, fetch(NewQuery(TestTable, "A").Offset(long+"2").OrderDesc()))
+	assert.Equal(t, []int{3}, fetch(NewQuery(TestTable, "A").Offset(long+"2")))
+	assert.Equal(t, []int{2}, fetch(NewQuery(TestTable, "A").After(long+"1").Before(long+"3").OrderDesc()))
+	assert.Equal(t, []int{2}, fetch(NewQuery(TestTable, "A").After(long+"1").Before(long+"3")))
+}
+
+func TestContext_Fetch_emptyPrefix(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, 1), 1)
+		tr.PutVar(Key(TestOrderedTable, 2), 2)
+		tr.Put([]byte{0xff, 0xff}, encodeValue(3))
+	})
+
+	var res []int
+	q := NewQuery(0).OrderDesc()
+	q.filter = nil // all keys
+	err := store.Fetch(q, func(rec Record) error {
+		res = append(res, int(rec.ValueInt()))
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 2, 1}, res)
+}
+
+func TestContext_Fetch_stats(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 100)
+
+	q := NewQuery(TestTable, "Key").FilterFn(func(rec Record) bool {
+		return rec.ValueStr() < "Value 2"
+	})
+	err := store.Fetch(q, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 18, int(q.NumRows)) // i = 0, 1, 0x10..0x1f
+	assert.Equal(t, 100, int(q.RowsScanned))
+	assert.True(t, q.BytesRead > 100*8)
+	assert.Equal(t, 1, int(q.Seeks))
+	assert.True(t, q.Elapsed > 0)
+}
+
+func TestQuery_Explain(t *testing.T) {
+	q := NewQuery(TestTable, "A").OrderDesc().Limit(10)
+
+	s := q.Explain()
+
+	assert.Contains(t, s, "scan range [014100, 014101) in descending order")
+	assert.Contains(t, s, "limit: 10")
+}
+
+func TestStorage_RemoveByQueryChunked(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 100)
+	store.PutVar(Key(TestTable, "Other"), "other")
+
+	var chunks []uint64
+	q := NewQuery(TestTable, "Key")
+	n, err := store.RemoveByQueryChunked(q, &RemoveOptions{
+		ChunkSize: 30,
+		Compact:   true,
+		Progress: func(numRemoved uint64, offset []byte) error {
+			chunks = append(chunks, numRemoved)
+			return nil
+		},
+	})
+	numRows, _ := store.GetNumRows(NewQuery(TestTable))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 100, int(n))
+	assert.Equal(t, []uint64{30, 60, 90, 100}, chunks)
+	assert.Equal(t, 1, int(numRows))
+}
+
+func TestStorage_RemoveByQueryChunked_compactOnBreak(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 100)
+
+	q := NewQuery(TestTable, "Key").OrderDesc()
+	n, err := store.RemoveByQueryChunked(q, &RemoveOptions{
+		ChunkSize: 30,
+		Compact:   true,
+		Progress: func(numRemoved uint64, offset []byte) error {
+			return Break
+		},
+	})
+	numRows, _ := store.GetNumRows(NewQuery(TestTable))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 30, int(n))
+	assert.Equal(t, 70, int(numRows))
+}
+
+func TestStorage_RemoveByQueryChunked_resume(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	putTestValues(store, 100)
+
+	q := NewQuery(TestTable, "Key")
+	n1, err1 := store.RemoveByQueryChunked(q, &RemoveOptions{
+		ChunkSize: 10,
+		Progress: func(numRemoved uint64, offset []byte) error {
+			if numRemoved == 50 {
+				return errors.New("interrupted")
+			}
+			return nil
+		},
+	})
+	n2, err2 := store.RemoveByQueryChunked(q, nil)
+	numRows, _ := store.GetNumRows(NewQuery(TestTable))
+
+	assert.Error(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, 50, int(n1))
+	assert.Equal(t, 50, int(n2))
+	assert.Equal(t, 0, int(numRows))
+}
+
 func fileExists(path string) bool {
 	st, _ := os.Stat(path)
 	return st != nil
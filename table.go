@@ -0,0 +1,92 @@
+//go:build go1.18
+
+package goldb
+
+import "errors"
+
+// ErrRangeKeyEncoding is error of Table.Range over numeric keys of entity without KeyEncodingOrdered
+var ErrRangeKeyEncoding = errors.New("goldb: range of numeric keys requires KeyEncodingOrdered")
+
+// Table is typed table of entity with key of type K and value of type V.
+// Table methods work both with Storage and Transaction.
+//
+//	var users = NewTable[uint64, User](TabUsers)
+//	err := users.Put(store, 1, User{"Alice", 22})
+//	user, ok, err := users.Get(store, 1)
+type Table[K, V any] struct {
+	Entity Entity
+}
+
+// NewTable returns typed table of entity
+func NewTable[K, V any](entityID Entity) Table[K, V] {
+	return Table[K, V]{entityID}
+}
+
+// Key returns key of table row
+func (t Table[K, V]) Key(key K) []byte {
+	return Key(t.Entity, key)
+}
+
+// Query returns new query to all rows of table
+func (t Table[K, V]) Query() *Query {
+	return NewQuery(t.Entity)
+}
+
+// Get returns value by key; ok is false when row does not exist
+func (t Table[K, V]) Get(r Reader, key K) (v V, ok bool, err error) {
+	data, err := r.Get(t.Key(key))
+	if err != nil || data == nil {
+		return
+	}
+	if err = decodeValue(data, &v); err == nil {
+		ok = true
+	}
+	return
+}
+
+// Put puts value by key
+func (t Table[K, V]) Put(w Writer, key K, v V) error {
+	return w.Put(t.Key(key), encodeValue(v))
+}
+
+// Delete deletes row by key
+func (t Table[K, V]) Delete(w Writer, key K) error {
+	return w.Delete(t.Key(key))
+}
+
+// Fetch fetches rows of table by query (all rows of table when q is nil)
+func (t Table[K, V]) Fetch(r Reader, q *Query, fn func(key K, v V) error) error {
+	if q == nil {
+		q = t.Query()
+	}
+	return r.Fetch(q, func(rec Record) error {
+		var key K
+		var v V
+		if err := rec.DecodeKey(&key); err != nil {
+			return err
+		}
+		if err := rec.Decode(&v); err != nil {
+			return err
+		}
+		return fn(key, v)
+	})
+}
+
+// Range fetches rows of table with keys from <= key < to.
+// Byte order of keys matches order of key-values only for strings and for entities with KeyEncodingOrdered,
+// so Range returns ErrRangeKeyEncoding for other keys.
+func (t Table[K, V]) Range(r Reader, from, to K, fn func(key K, v V) error) error {
+	switch any(from).(type) {
+	case string, []byte:
+	default:
+		if t.Entity.KeyEncoding() != KeyEncodingOrdered {
+			return ErrRangeKeyEncoding
+		}
+	}
+	return t.Fetch(r, t.Query().Between(from, to), fn)
+}
+
+// Count returns number of rows in table
+func (t Table[K, V]) Count(r Reader) (uint64, error) {
+	return r.GetNumRows(t.Query())
+}
@@ -0,0 +1,66 @@
+//go:build go1.18
+
+package goldb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTable(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	users := NewTable[int, User](TestOrderedTable)
+
+	err := store.Exec(func(tr *Transaction) {
+		users.Put(tr, -1, User{"Alice", 22})
+		users.Put(tr, 2, User{"Bob", 33})
+		users.Put(tr, 3, User{"Cat", 4})
+	})
+	errPut := users.Put(store, 4, User{"Dan", 55})
+	errDel := users.Delete(store, 3)
+	alice, ok, errGet := users.Get(store, -1)
+	_, okCat, _ := users.Get(store, 3)
+	count, errCount := users.Count(store)
+	var keys []int
+	var names []string
+	errRange := users.Range(store, -1, 4, func(id int, u User) error {
+		keys = append(keys, id)
+		names = append(names, u.Name)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, errPut)
+	assert.NoError(t, errDel)
+	assert.NoError(t, errGet)
+	assert.NoError(t, errCount)
+	assert.NoError(t, errRange)
+	assert.True(t, ok)
+	assert.False(t, okCat)
+	assert.Equal(t, User{"Alice", 22}, alice)
+	assert.Equal(t, 3, int(count))
+	assert.Equal(t, []int{-1, 2}, keys)
+	assert.Equal(t, []string{"Alice", "Bob"}, names)
+}
+
+func TestTable_Range_keyEncoding(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	users := NewTable[int, User](TestTable)
+	names := NewTable[string, User](TestTable)
+	users.Put(store, -1, User{"Alice", 22})
+	names.Put(store, "Bob", User{"Bob", 33})
+
+	errInt := users.Range(store, -1, 4, func(id int, u User) error { return nil })
+	var keys []string
+	errStr := names.Range(store, "A", "C", func(name string, u User) error {
+		keys = append(keys, name)
+		return nil
+	})
+
+	assert.Equal(t, ErrRangeKeyEncoding, errInt)
+	assert.NoError(t, errStr)
+	assert.Equal(t, []string{"Bob"}, keys)
+}
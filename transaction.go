@@ -1,6 +1,7 @@
 package goldb
 
 import (
+	"bytes"
 	"math/big"
 
 	"github.com/syndtr/goleveldb/leveldb"
@@ -11,6 +12,9 @@ type Transaction struct {
 	tr           *leveldb.Transaction
 	seq          map[Entity]uint64
 	countUpdates int64
+
+	fTrackChanges bool
+	changes       []Change
 }
 
 func (t *Transaction) Discard() {
@@ -33,6 +37,9 @@ func panicOnErr(err error) {
 
 const tabSequences Entity = 0x7fffffff
 
+// minSystemEntity is lower bound of entities reserved for system data (up to tabSequences)
+const minSystemEntity Entity = 0x7fffff00
+
 func (t *Transaction) SequenceCurVal(tab Entity) (seq uint64) {
 	if t.seq == nil {
 		t.seq = map[Entity]uint64{}
@@ -56,10 +63,11 @@ func (t *Transaction) SequenceNextVal(tab Entity) (seq uint64) {
 }
 
 func (t *Transaction) Put(key, data []byte) error {
-	if err := t.tr.Put(key, data, t.WriteOptions); err != nil {
-		t.Fail(err)
+	if idxs := keyIndexes(key); idxs != nil {
+		t.updateIndexes(idxs, key, data, false)
 	}
-	t.countUpdates++
+	t.clearTTL(key)
+	t.put(key, data)
 	return nil
 }
 
@@ -101,13 +109,62 @@ func (t *Transaction) IncrementBig(key []byte, delta *big.Int) *big.Int {
 }
 
 func (t *Transaction) Delete(key []byte) error {
-	if err := t.tr.Delete(key, t.WriteOptions); err != nil {
-		t.Fail(err)
+	if idxs := keyIndexes(key); idxs != nil {
+		t.updateIndexes(idxs, key, nil, true)
 	}
-	t.countUpdates++
+	t.clearTTL(key)
+	t.delete(key)
 	return nil
 }
 
+// PutIfAbsent puts data by key when the key does not exist; returns true when data was put
+func (t *Transaction) PutIfAbsent(key, data []byte) bool {
+	if old, _ := t.Get(key); old != nil {
+		return false
+	}
+	t.Put(key, data)
+	return true
+}
+
+// CompareAndSwap puts newData by key when current value equals to oldData
+// (oldData is nil - the key must not exist); returns true when data was put
+func (t *Transaction) CompareAndSwap(key, oldData, newData []byte) bool {
+	if v, _ := t.Get(key); (v == nil) != (oldData == nil) || !bytes.Equal(v, oldData) {
+		return false
+	}
+	t.Put(key, newData)
+	return true
+}
+
+// DeleteIfEquals deletes key when its value equals to data; returns true when the key was deleted
+func (t *Transaction) DeleteIfEquals(key, data []byte) bool {
+	if v, _ := t.Get(key); v == nil || !bytes.Equal(v, data) {
+		return false
+	}
+	t.Delete(key)
+	return true
+}
+
 func (t *Transaction) CountUpdates() int64 {
 	return t.countUpdates
 }
+
+func (t *Transaction) put(key, data []byte) {
+	if err := t.tr.Put(key, data, t.WriteOptions); err != nil {
+		t.Fail(err)
+	}
+	t.countUpdates++
+	if t.fTrackChanges {
+		t.changes = append(t.changes, Change{OpPut, append([]byte{}, key...), append([]byte{}, data...)})
+	}
+}
+
+func (t *Transaction) delete(key []byte) {
+	if err := t.tr.Delete(key, t.WriteOptions); err != nil {
+		t.Fail(err)
+	}
+	t.countUpdates++
+	if t.fTrackChanges {
+		t.changes = append(t.changes, Change{OpDelete, append([]byte{}, key...), nil})
+	}
+}
@@ -159,3 +159,34 @@ func TestTransaction_Exec_fail(t *testing.T) {
 
 	assert.Error(t, err)
 }
+
+func TestTransaction_CompareAndSwap(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	key := Key(TestTable, "id")
+
+	ok1, err1 := store.PutIfAbsent(key, []byte("A"))
+	ok2, err2 := store.PutIfAbsent(key, []byte("B"))
+	ok3, err3 := store.CompareAndSwap(key, []byte("B"), []byte("C"))
+	ok4, err4 := store.CompareAndSwap(key, []byte("A"), []byte("C"))
+	ok5, err5 := store.DeleteIfEquals(key, []byte("A"))
+	v, _ := store.Get(key)
+	ok6, err6 := store.DeleteIfEquals(key, []byte("C"))
+	ok7, err7 := store.CompareAndSwap(key, nil, []byte("D"))
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.NoError(t, err3)
+	assert.NoError(t, err4)
+	assert.NoError(t, err5)
+	assert.NoError(t, err6)
+	assert.NoError(t, err7)
+	assert.True(t, ok1)
+	assert.False(t, ok2)
+	assert.False(t, ok3)
+	assert.True(t, ok4)
+	assert.False(t, ok5)
+	assert.Equal(t, []byte("C"), v)
+	assert.True(t, ok6)
+	assert.True(t, ok7)
+}
@@ -0,0 +1,201 @@
+package goldb
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	tabExpires     Entity = 0x7ffffffe // key -> expiry time (unix nano)
+	tabExpiryIndex Entity = 0x7ffffffd // (expiry time, key) -> nil
+	tabTTLEntities Entity = 0x7ffffffa // (entity) -> nil; entities having keys with ttl
+)
+
+// ttlEntities is set of entities having keys with ttl.
+// Expiry of keys is checked only for these entities.
+type ttlEntities struct {
+	sync.RWMutex
+	m map[Entity]bool
+}
+
+func (e *ttlEntities) has(key []byte) bool {
+	if e == nil {
+		return false
+	}
+	e.RLock()
+	defer e.RUnlock()
+	if len(e.m) == 0 {
+		return false
+	}
+	tab, err := decodeUint(key)
+	return err == nil && e.m[Entity(tab)]
+}
+
+func (e *ttlEntities) add(tab Entity) {
+	e.Lock()
+	defer e.Unlock()
+	if e.m == nil {
+		e.m = map[Entity]bool{}
+	}
+	e.m[tab] = true
+}
+
+func (e *ttlEntities) set(m map[Entity]bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.m = m
+}
+
+// timeNow returns current time of ttl checks (replaced in tests)
+var timeNow = time.Now
+
+func init() {
+	SetKeyEncoding(tabExpiryIndex, KeyEncodingOrdered)
+}
+
+// PutWithTTL puts data by key. The key is treated as absent after ttl and removed by expiry reaper
+func (t *Transaction) PutWithTTL(key, data []byte, ttl time.Duration) error {
+	t.Put(key, data)
+	t.setTTL(key, timeNow().Add(ttl))
+	return nil
+}
+
+// setTTL sets expiry time of existing key
+func (t *Transaction) setTTL(key []byte, deadline time.Time) {
+	t.clearTTL(key)
+	t.put(Key(tabExpires, key), encodeValue(deadline.UnixNano()))
+	t.put(Key(tabExpiryIndex, deadline, key), nil)
+	if tab, err := decodeUint(key); err == nil {
+		if data, _ := t.getRaw(Key(tabTTLEntities, int(tab))); data == nil {
+			t.put(Key(tabTTLEntities, int(tab)), nil)
+		}
+		t.fTTL.add(Entity(tab))
+	}
+}
+
+// PutWithTTL puts data by key. The key is treated as absent after ttl and removed by expiry reaper
+func (s *Storage) PutWithTTL(key, data []byte, ttl time.Duration) error {
+	return s.ExecBatch(func(tr *Transaction) {
+		tr.PutWithTTL(key, data, ttl)
+	})
+}
+
+// RemoveExpired deletes up to limit expired keys. Returns number of deleted keys
+func (s *Storage) RemoveExpired(limit int) (n int, err error) {
+	var keys [][]byte
+	q := NewQuery(tabExpiryIndex).Until(Exclusive, timeNow()).Limit(int64(limit))
+	err = s.Fetch(q, func(rec Record) error {
+		var deadline time.Time
+		var key []byte
+		if err := rec.DecodeKey(&deadline, &key); err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil || len(keys) == 0 {
+		return
+	}
+	err = s.ExecBatch(func(tr *Transaction) {
+		for _, key := range keys {
+			if tr.expired(key) {
+				tr.Delete(key)
+				n++
+			}
+		}
+	})
+	return
+}
+
+// StartExpiryReaper starts background removing of expired keys every interval by batches of batchSize keys
+func (s *Storage) StartExpiryReaper(interval time.Duration, batchSize int) {
+	s.StopExpiryReaper()
+
+	s.reaperMx.Lock()
+	defer s.reaperMx.Unlock()
+
+	stop := make(chan struct{})
+	s.reaperStop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				for {
+					n, err := s.RemoveExpired(batchSize)
+					if err != nil && err != leveldb.ErrClosed {
+						log.Println("!!! db.Storage.RemoveExpired-ERROR: ", err)
+					}
+					if err != nil || n < batchSize {
+						break
+					}
+				}
+			}
+		}
+	}()
+}
+
+// StopExpiryReaper stops background removing of expired keys
+func (s *Storage) StopExpiryReaper() {
+	s.reaperMx.Lock()
+	defer s.reaperMx.Unlock()
+
+	if s.reaperStop != nil {
+		close(s.reaperStop)
+		s.reaperStop = nil
+	}
+}
+
+func (s *Storage) initTTL() {
+	s.fTTL = &s.ttlTabs
+	tabs := map[Entity]bool{}
+	iter := s.db.NewIterator(util.BytesPrefix(Key(tabTTLEntities)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		var tab int
+		if err := (Record{Key: iter.Key()}).DecodeKey(&tab); err == nil {
+			tabs[Entity(tab)] = true
+		}
+	}
+	s.ttlTabs.set(tabs)
+}
+
+// expired returns true when key has expired ttl
+func (c *context) expired(key []byte) bool {
+	return c.expiredIn(c.qCtx, key)
+}
+
+// expiredIn returns true when key has expired ttl in source of data
+func (c *context) expiredIn(src queryContext, key []byte) bool {
+	if !c.fTTL.has(key) {
+		return false
+	}
+	data, err := src.Get(Key(tabExpires, key), c.ReadOptions)
+	if err != nil {
+		return false
+	}
+	var deadline int64
+	if err = decodeValue(data, &deadline); err != nil {
+		return false
+	}
+	return deadline <= timeNow().UnixNano()
+}
+
+// clearTTL removes ttl of key
+func (t *Transaction) clearTTL(key []byte) {
+	if !t.fTTL.has(key) {
+		return
+	}
+	var deadline int64
+	if data, _ := t.getRaw(Key(tabExpires, key)); data != nil && decodeValue(data, &deadline) == nil {
+		t.delete(Key(tabExpires, key))
+		t.delete(Key(tabExpiryIndex, time.Unix(0, deadline), key))
+	}
+}
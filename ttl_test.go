@@ -0,0 +1,106 @@
+package goldb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// shiftTime shifts clock of ttl checks by d; returned func restores the clock
+func shiftTime(d time.Duration) (restore func()) {
+	now := timeNow
+	timeNow = func() time.Time { return now().Add(d) }
+	return func() { timeNow = now }
+}
+
+func TestStorage_PutWithTTL(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	err1 := store.PutWithTTL(Key(TestTable, "A"), []byte("Alice"), time.Millisecond)
+	err2 := store.PutWithTTL(Key(TestTable, "B"), []byte("Bob"), time.Hour)
+	err3 := store.PutWithTTL(Key(TestTable, "C"), []byte("Cat"), time.Millisecond)
+	err4 := store.Put(Key(TestTable, "C"), []byte("Cat")) // clear ttl
+	defer shiftTime(time.Minute)()
+
+	a, _ := store.Get(Key(TestTable, "A"))
+	b, _ := store.Get(Key(TestTable, "B"))
+	numRows, _ := store.GetNumRows(NewQuery(TestTable))
+	n, errRemove := store.RemoveExpired(100)
+	numExpiryRows, _ := store.GetNumRows(NewQuery(tabExpiryIndex))
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.NoError(t, err3)
+	assert.NoError(t, err4)
+	assert.NoError(t, errRemove)
+	assert.Nil(t, a)
+	assert.Equal(t, []byte("Bob"), b)
+	assert.Equal(t, 2, int(numRows))
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, int(numExpiryRows))
+}
+
+func TestStorage_StartExpiryReaper(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	restore := shiftTime(-time.Minute) // keys are expired by real clock
+	for i := 0; i < 10; i++ {
+		store.PutWithTTL(Key(TestTable, i), []byte("value"), time.Millisecond)
+	}
+	restore()
+
+	store.StartExpiryReaper(time.Millisecond, 3)
+	numRows, _ := store.GetNumRows(NewQuery(tabExpires))
+	for i := 0; i < 1000 && numRows > 0; i++ { // wait for reaper
+		time.Sleep(time.Millisecond)
+		numRows, _ = store.GetNumRows(NewQuery(tabExpires))
+	}
+	store.StopExpiryReaper()
+
+	assert.Equal(t, 0, int(numRows))
+}
+
+func TestStorage_PutWithTTL_indexes(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	store.PutWithTTL(Key(testIndexedTable, 1), encodeValue(User{"Alice", 22}), time.Millisecond)
+	store.PutWithTTL(Key(testIndexedTable, 2), encodeValue(User{"Bob", 33}), time.Millisecond)
+	defer shiftTime(time.Minute)()
+	err1 := store.PutVar(Key(testIndexedTable, 1), User{"Alina", 22}) // overwrite expired row
+	n, err2 := store.RemoveExpired(100)
+	err3 := store.PutVar(Key(testIndexedTable, 3), User{"Bob", 44}) // reuse unique name of removed row
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.NoError(t, err3)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string(nil), fetchUserNames(store, NewIndexQuery(testIdxUserName, "Alice")))
+	assert.Equal(t, []string{"Alina"}, fetchUserNames(store, NewIndexQuery(testIdxUserAge, 22)))
+	assert.Equal(t, []string(nil), fetchUserNames(store, NewIndexQuery(testIdxUserAge, 33)))
+	assert.Equal(t, []string{"Bob"}, fetchUserNames(store, NewIndexQuery(testIdxUserName, "Bob")))
+}
+
+func TestStorage_PutWithTTL_entities(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	store.PutWithTTL(Key(TestTable, "A"), []byte("Alice"), time.Hour)
+	store.Close()
+	store.Open()
+
+	assert.True(t, store.fTTL.has(Key(TestTable, "B")))
+	assert.False(t, store.fTTL.has(Key(TestTable+1, "B")))
+}
+
+func TestStorage_Close_stopsExpiryReaper(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.StartExpiryReaper(time.Millisecond, 10)
+
+	store.Close()
+
+	assert.Nil(t, store.reaperStop)
+}
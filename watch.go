@@ -0,0 +1,125 @@
+package goldb
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+)
+
+// ChangeOp is type of change of data
+type ChangeOp int
+
+const (
+	OpPut ChangeOp = iota + 1
+	OpDelete
+)
+
+// Change is committed change of data
+type Change struct {
+	Op    ChangeOp
+	Key   []byte
+	Value []byte // nil for OpDelete
+}
+
+// WatchBufferSize is default size of buffer of watcher
+const WatchBufferSize = 1024
+
+var ErrWatcherOverflow = errors.New("goldb: watcher buffer overflow")
+
+// Watcher receives changes committed by Exec and ExecBatch for keys with prefix.
+// Writers never wait for watchers: when buffer of watcher is full,
+// the watcher is closed with error ErrWatcherOverflow and channel C is closed.
+type Watcher struct {
+	C      <-chan Change
+	c      chan Change
+	prefix []byte
+	store  *Storage
+	err    error
+}
+
+// Watch returns watcher of committed changes of keys with prefix (nil prefix - all keys)
+func (s *Storage) Watch(prefix []byte) *Watcher {
+	return s.WatchBuffered(prefix, WatchBufferSize)
+}
+
+// WatchBuffered returns watcher of committed changes of keys with prefix with buffer of bufSize changes
+func (s *Storage) WatchBuffered(prefix []byte, bufSize int) *Watcher {
+	c := make(chan Change, bufSize)
+	w := &Watcher{
+		C:      c,
+		c:      c,
+		prefix: append([]byte{}, prefix...),
+		store:  s,
+	}
+
+	s.watchMx.Lock()
+	defer s.watchMx.Unlock()
+
+	if s.watchers == nil {
+		s.watchers = map[*Watcher]struct{}{}
+	}
+	s.watchers[w] = struct{}{}
+	atomic.StoreInt32(&s.cntWatchers, int32(len(s.watchers)))
+	return w
+}
+
+// Err returns error of closed watcher
+func (w *Watcher) Err() error {
+	w.store.watchMx.Lock()
+	defer w.store.watchMx.Unlock()
+	return w.err
+}
+
+// Close stops watching and closes channel C
+func (w *Watcher) Close() {
+	w.store.watchMx.Lock()
+	defer w.store.watchMx.Unlock()
+	w.close(nil)
+}
+
+func (w *Watcher) close(err error) {
+	s := w.store
+	if _, ok := s.watchers[w]; ok {
+		delete(s.watchers, w)
+		atomic.StoreInt32(&s.cntWatchers, int32(len(s.watchers)))
+		w.err = err
+		close(w.c)
+	}
+}
+
+// closeWatchers closes all watchers with error err
+func (s *Storage) closeWatchers(err error) {
+	s.watchMx.Lock()
+	defer s.watchMx.Unlock()
+
+	for w := range s.watchers {
+		w.close(err)
+	}
+}
+
+func (s *Storage) hasWatchers() bool {
+	return atomic.LoadInt32(&s.cntWatchers) > 0
+}
+
+func (s *Storage) notifyWatchers(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+	s.watchMx.Lock()
+	defer s.watchMx.Unlock()
+
+	for w := range s.watchers {
+	sending:
+		for _, c := range changes {
+			if !bytes.HasPrefix(c.Key, w.prefix) {
+				continue
+			}
+			select {
+			case w.c <- c:
+			default: // slow consumer
+				w.close(ErrWatcherOverflow)
+				break sending
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package goldb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestStorage_Watch(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	w := store.Watch(Key(TestTable, "A"))
+	defer w.Close()
+
+	store.PutVar(Key(TestTable, "A", 1), "Alice")
+	store.PutVar(Key(TestTable, "B", 2), "Bob")
+	store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, "A", 3), "Alina")
+		tr.Fail(errors.New("transaction-fail")) // discard transaction
+	})
+	store.Delete(Key(TestTable, "A", 1))
+
+	c1, c2 := <-w.C, <-w.C
+
+	assert.Equal(t, Change{OpPut, Key(TestTable, "A", 1), encodeValue("Alice")}, c1)
+	assert.Equal(t, Change{OpDelete, Key(TestTable, "A", 1), nil}, c2)
+	assert.Equal(t, 0, len(w.C))
+}
+
+func TestStorage_Watch_overflow(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	w := store.WatchBuffered(nil, 2)
+	store.Exec(func(tr *Transaction) {
+		tr.PutVar(Key(TestTable, 1), 1)
+		tr.PutVar(Key(TestTable, 2), 2)
+		tr.PutVar(Key(TestTable, 3), 3)
+	})
+	var n int
+	for range w.C {
+		n++
+	}
+
+	assert.Equal(t, 2, n)
+	assert.Equal(t, ErrWatcherOverflow, w.Err())
+}
+
+func TestStorage_Close_closesWatchers(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	w := store.Watch(nil)
+
+	store.Close()
+	_, ok := <-w.C
+
+	assert.False(t, ok)
+	assert.Equal(t, leveldb.ErrClosed, w.Err())
+}